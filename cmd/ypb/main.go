@@ -3,18 +3,24 @@ package main
 import (
 	"log/slog"
 	"os"
+	"strings"
+	_ "time/tzdata"
 
 	"github.com/alecthomas/kong"
 
 	"github.com/xymaxim/ypb/internal/commands"
 	"github.com/xymaxim/ypb/internal/commands/capture"
+	"github.com/xymaxim/ypb/internal/playback/fetchers"
 )
 
 type CLI struct {
-	Verbose int `help:"Show verbose output." short:"v" type:"counter"`
+	Verbose int    `help:"Show verbose output." short:"v" type:"counter"`
+	Tz      string `help:"Time zone to read and show times in: IANA name (e.g., Asia/Tokyo), local, or stream"`
 
 	Capture  CaptureCommands   `cmd:"" help:"Capture single frame or time-lapse sequence"`
 	Download commands.Download `cmd:"" help:"Download stream excerpts"`
+	Gaps     commands.Gaps     `cmd:"" help:"List gaps in stream timeline"`
+	Record   commands.Record   `cmd:"" help:"Record stream following its head"`
 	Serve    commands.Serve    `cmd:"" help:"Start playback server"`
 	Version  commands.Version  `cmd:"" help:"Show version info and exit"`
 }
@@ -33,11 +39,15 @@ func main() {
 		kong.Name("ypb"),
 		kong.Description("A playback for YouTube live streams"),
 		kong.UsageOnError(),
+		kong.Vars{"fetchers": strings.Join(fetchers.Names(), ",")},
 	)
 
 	setupLogging(cli.Verbose)
 
-	err := kongCtx.Run()
+	tz, err := commands.NewTimezone(cli.Tz)
+	kongCtx.FatalIfErrorf(err)
+
+	err = kongCtx.Run(tz)
 	kongCtx.FatalIfErrorf(err)
 }
 
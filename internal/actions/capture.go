@@ -3,6 +3,7 @@ package actions
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -12,17 +13,28 @@ import (
 	"github.com/xymaxim/ypb/internal/playback"
 )
 
-// CaptureFrame extracts a frame corresponding to a moment.
+// ErrNoVideoStreams is returned when there is no video stream to capture frames
+// from, e.g., after filtering out all video streams.
+var ErrNoVideoStreams = errors.New("no video streams match the filter")
+
+// CaptureFrame extracts a frame corresponding to a moment. The metadata, if
+// not nil, is written for the frame.
 func CaptureFrame(
 	pb playback.Playbacker,
 	moment *playback.RewindMoment,
 	outputPath string,
+	metadata *FrameMetadata,
 	runner exec.Runner,
 ) error {
+	itag, err := videoItag(pb)
+	if err != nil {
+		return err
+	}
+
 	var buf bytes.Buffer
 
-	err := pb.StreamSegment(
-		pb.Info().BestVideo().Itag,
+	err = pb.StreamSegment(
+		itag,
 		moment.Metadata.SequenceNumber,
 		&buf,
 	)
@@ -39,34 +51,69 @@ func CaptureFrame(
 		return fmt.Errorf("extracting frame: %w", err)
 	}
 
+	if err := metadata.write(outputPath, moment); err != nil {
+		return fmt.Errorf("writing frame metadata: %w", err)
+	}
+
 	return nil
 }
 
+// CaptureFrames extracts frames corresponding to the times. The outputPath
+// function returns the path of a frame by its index, or an empty path to skip
+// the frame. The metadata, if not nil, is written for each frame.
+//
+// Frames are located one by one as they are captured, each search starting
+// from the frame before. A frame that cannot be located is skipped, and the
+// errors of such frames are returned after all other frames are captured.
 func CaptureFrames(
 	pb playback.Playbacker,
 	times []time.Time,
 	locateContext *LocateContext,
-	outputPattern string,
+	outputPath func(index int) (string, error),
+	metadata *FrameMetadata,
 	runner exec.Runner,
 	onFrame func(index int, skipped bool),
 ) (captured, skipped int, err error) {
+	itag, err := videoItag(pb)
+	if err != nil {
+		return captured, skipped, err
+	}
+
 	var previousSq playback.SequenceNumber
 	var previousSegment []byte
 
-	reference := locateContext.Head
+	var errs []error
+	locator := newMomentLocator(pb, locateContext)
 
 	for frameIndex, t := range times {
-		rewindMoment, err := pb.LocateMoment(t, reference, false)
+		rewindMoment, err := locator.locate(t)
 		if err != nil {
-			return captured, skipped, fmt.Errorf(
-				"frame %d at %s: locating moment: %w",
-				frameIndex,
-				t,
-				err,
+			slog.Error(
+				"failed to locate frame",
+				"frame", frameIndex,
+				"time", t,
+				"err", err,
 			)
+			errs = append(errs, fmt.Errorf("frame %d at %s: %w", frameIndex, t, err))
+			skipped++
+			if onFrame != nil {
+				onFrame(frameIndex, true)
+			}
+			continue
 		}
 
-		if rewindMoment.InGap {
+		var framePath string
+		if !rewindMoment.InGap {
+			framePath, err = outputPath(frameIndex)
+			if err != nil {
+				return captured, skipped, fmt.Errorf(
+					"frame %d: building output path: %w",
+					frameIndex,
+					err,
+				)
+			}
+		}
+		if framePath == "" {
 			skipped++
 			if onFrame != nil {
 				onFrame(frameIndex, true)
@@ -80,7 +127,7 @@ func CaptureFrames(
 		if previousSegment == nil || previousSq != sq {
 			var buf bytes.Buffer
 			if err := pb.StreamSegment(
-				pb.Info().BestVideo().Itag,
+				itag,
 				sq,
 				&buf,
 			); err != nil {
@@ -96,10 +143,9 @@ func CaptureFrames(
 			previousSegment = buf.Bytes()
 		}
 
-		outputPath := fmt.Sprintf(outputPattern, frameIndex)
 		if err := extractFrame(
 			rewindMoment,
-			outputPath,
+			framePath,
 			previousSegment,
 			runner,
 		); err != nil {
@@ -111,14 +157,31 @@ func CaptureFrames(
 			)
 		}
 
+		if err := metadata.write(framePath, rewindMoment); err != nil {
+			return captured, skipped, fmt.Errorf(
+				"frame %d at %s: writing metadata: %w",
+				frameIndex,
+				t,
+				err,
+			)
+		}
+
 		captured++
-		reference = rewindMoment.Metadata
 
 		if onFrame != nil {
 			onFrame(frameIndex, false)
 		}
 	}
 
+	if len(errs) > 0 {
+		return captured, skipped, fmt.Errorf(
+			"%d of %d frames could not be located: %w",
+			len(errs),
+			len(times),
+			errors.Join(errs...),
+		)
+	}
+
 	return captured, skipped, nil
 }
 
@@ -204,3 +267,12 @@ func extractLastFrame(outputPath string, segment []byte, runner exec.Runner) err
 
 	return nil
 }
+
+// videoItag returns the itag of the best video stream to capture frames from.
+func videoItag(pb playback.Playbacker) (string, error) {
+	video := pb.Info().BestVideo()
+	if video == nil {
+		return "", ErrNoVideoStreams
+	}
+	return video.Itag, nil
+}
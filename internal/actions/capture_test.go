@@ -0,0 +1,111 @@
+package actions_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func TestCaptureFrames_NoVideoStreams(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(10, 2*time.Second)
+	pb := newFakePlayback(fakeMetadata)
+
+	locateContext, err := actions.NewLocateContext(pb, nil, nil)
+	require.NoError(t, err)
+
+	_, _, err = actions.CaptureFrames(
+		pb,
+		[]time.Time{fakeMetadata[5].IngestionWalltime},
+		locateContext,
+		func(int) (string, error) { return "frame.png", nil },
+		nil,
+		nil,
+		nil,
+	)
+	require.ErrorIs(t, err, actions.ErrNoVideoStreams)
+
+	err = actions.CaptureFrame(pb, nil, "frame.png", nil, nil)
+	require.ErrorIs(t, err, actions.ErrNoVideoStreams)
+}
+
+// videoPlayback is a fake playback with a video stream, failing to locate the
+// given times.
+type videoPlayback struct {
+	*fakePlayback
+	failing map[time.Time]bool
+}
+
+func (pb *videoPlayback) Info() info.VideoInformation {
+	return info.VideoInformation{
+		VideoStreams: []info.VideoStream{{CommonStream: info.CommonStream{Itag: "137"}}},
+	}
+}
+
+func (pb *videoPlayback) StreamSegment(_ string, _ playback.SequenceNumber, w io.Writer) error {
+	_, err := w.Write([]byte("segment"))
+	return err
+}
+
+func (pb *videoPlayback) LocateMoment(
+	t time.Time,
+	reference segment.Metadata,
+	isEnd bool,
+) (*playback.RewindMoment, error) {
+	if pb.failing[t] {
+		return nil, errors.New("locate failed")
+	}
+	return pb.fakePlayback.LocateMoment(t, reference, isEnd)
+}
+
+func TestCaptureFrames_LocateFailure(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(10, 2*time.Second)
+	times := []time.Time{
+		fakeMetadata[2].IngestionWalltime,
+		fakeMetadata[4].IngestionWalltime,
+		fakeMetadata[6].IngestionWalltime,
+	}
+	pb := &videoPlayback{
+		fakePlayback: newFakePlayback(fakeMetadata),
+		failing:      map[time.Time]bool{times[1]: true},
+	}
+
+	locateContext, err := actions.NewLocateContext(pb, nil, nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	var progress []int
+	captured, skipped, err := actions.CaptureFrames(
+		pb,
+		times,
+		locateContext,
+		func(index int) (string, error) {
+			return filepath.Join(dir, fmt.Sprintf("frame%d.png", index)), nil
+		},
+		nil,
+		&recordingRunner{},
+		func(index int, _ bool) { progress = append(progress, index) },
+	)
+
+	// Other frames are captured, and the failed one is reported in the end
+	require.ErrorContains(t, err, "1 of 3 frames could not be located")
+	assert.Equal(t, 2, captured)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, []int{0, 1, 2}, progress)
+	assert.FileExists(t, filepath.Join(dir, "frame2.png"))
+}
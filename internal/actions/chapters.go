@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/input"
+	"github.com/xymaxim/ypb/internal/mpd"
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+// Chapter is a titled point of a located interval.
+type Chapter struct {
+	// Offset is the time of the chapter from the actual start of the interval.
+	Offset time.Duration `json:"offset"`
+	Title  string        `json:"title"`
+}
+
+// ChapterMark is a chapter to locate: a moment value and a title. A duration
+// value is taken as an offset from the actual start of the interval.
+type ChapterMark struct {
+	Value input.MomentValue
+	Title string
+}
+
+// LocateChapters resolves chapter marks within the interval and returns the
+// chapters ordered by offset. Marks outside the interval are skipped.
+func LocateChapters(
+	pb playback.Playbacker,
+	interval *playback.RewindInterval,
+	marks []ChapterMark,
+	ctx *LocateContext,
+) ([]Chapter, error) {
+	chapters := make([]Chapter, 0, len(marks))
+
+	for _, mark := range marks {
+		offset, ok := mark.Value.(time.Duration)
+		if !ok {
+			moment, err := LocateMoment(pb, mark.Value, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("locating chapter %q: %w", mark.Title, err)
+			}
+			// Keep the precision of a target within the located segment
+			at := moment.ActualTime
+			if !moment.InGap && segmentContains(moment.Metadata, moment.TargetTime) {
+				at = moment.TargetTime
+			}
+			offset = at.Sub(interval.Start.ActualTime)
+		}
+
+		if offset < 0 || offset >= interval.Duration() {
+			slog.Warn(
+				"skipping chapter outside interval",
+				"title", mark.Title,
+				"offset", offset,
+			)
+			continue
+		}
+		chapters = append(chapters, Chapter{Offset: offset, Title: mark.Title})
+	}
+
+	slices.SortStableFunc(chapters, func(a, b Chapter) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+
+	return chapters, nil
+}
+
+// MPDChapters converts chapters to ones of a manifest, each lasting until the
+// next one or the end of the media.
+func MPDChapters(chapters []Chapter, duration time.Duration) []mpd.Chapter {
+	out := make([]mpd.Chapter, len(chapters))
+	for i, chapter := range chapters {
+		out[i] = mpd.Chapter{
+			Offset:   chapter.Offset,
+			Duration: chapterEnd(chapters, i, duration) - chapter.Offset,
+			Title:    chapter.Title,
+		}
+	}
+	return out
+}
+
+func chapterEnd(chapters []Chapter, i int, duration time.Duration) time.Duration {
+	if i+1 < len(chapters) {
+		return chapters[i+1].Offset
+	}
+	return duration
+}
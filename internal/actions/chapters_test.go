@@ -0,0 +1,42 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func TestLocateChapters(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(5, 2*time.Second)
+	pb := newFakePlayback(fakeMetadata)
+	head := fakeMetadata[len(fakeMetadata)-1]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	start, end := fakeMetadata[1], fakeMetadata[3]
+	interval := &playback.RewindInterval{
+		Start: playback.NewRewindMoment(start.Time(), start, false, false),
+		End:   playback.NewRewindMoment(end.EndTime(), end, true, false),
+	}
+
+	marks := []actions.ChapterMark{
+		{Value: time.Date(2026, 1, 2, 10, 20, 35, 500_000_000, time.UTC), Title: "Second"},
+		{Value: time.Second, Title: "First"},
+		{Value: time.Date(2026, 1, 2, 10, 20, 31, 0, time.UTC), Title: "Before"},
+		{Value: time.Minute, Title: "After"},
+	}
+
+	chapters, err := actions.LocateChapters(pb, interval, marks, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []actions.Chapter{
+		{Offset: time.Second, Title: "First"},
+		{Offset: 3500 * time.Millisecond, Title: "Second"},
+	}, chapters)
+}
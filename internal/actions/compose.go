@@ -11,11 +11,24 @@ import (
 	"github.com/xymaxim/ypb/internal/exec"
 	"github.com/xymaxim/ypb/internal/mpd"
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/segment"
 )
 
+const (
+	// windowUpdatePeriod is how often players should reload time-shift
+	// manifests, re-anchoring them to the actual stream timeline.
+	windowUpdatePeriod = 30 * time.Second
+	// windowPresentationDelay is the number of segments players should stay
+	// behind the live edge of time-shift manifests.
+	windowPresentationDelay = 3
+)
+
+// ComposeStatic composes a static MPD of the interval. Chapters, if any, are
+// listed in an event stream.
 func ComposeStatic(
 	pb playback.Playbacker,
 	interval *playback.RewindInterval,
+	chapters []Chapter,
 	baseURL string,
 	runner exec.Runner,
 ) ([]byte, error) {
@@ -26,6 +39,11 @@ func ComposeStatic(
 		return nil, fmt.Errorf("extracting pts: %w", err)
 	}
 
+	timeline, err := LocateTimelineRuns(pb, interval)
+	if err != nil {
+		return nil, fmt.Errorf("locating timeline runs: %w", err)
+	}
+
 	out, err := mpd.ComposeStatic(mpd.StaticOptions{
 		CommonOptions: mpd.CommonOptions{
 			BaseURL:         baseURL,
@@ -34,7 +52,8 @@ func ComposeStatic(
 			PTS:             pts,
 		},
 		MediaDuration: interval.Duration(),
-		SegmentCount:  interval.End.Metadata.SequenceNumber - startNumber + 1,
+		Timeline:      timeline,
+		Chapters:      MPDChapters(chapters, interval.Duration()),
 	}, pb.Info())
 	if err != nil {
 		return nil, fmt.Errorf("composing mpd: %w", err)
@@ -43,10 +62,55 @@ func ComposeStatic(
 	return []byte(out), nil
 }
 
+// LocateTimelineRuns splits the segments of an interval into runs separated
+// by gaps, so that the presentation timeline follows the walltime. Gaps are
+// found by walking every segment of the interval, since the timeline may catch
+// up with a gap later and hide it from the elapsed time of the interval.
+func LocateTimelineRuns(
+	pb playback.Playbacker,
+	interval *playback.RewindInterval,
+) ([]mpd.SegmentRun, error) {
+	start, end := interval.Start.Metadata, interval.End.Metadata
+
+	gaps, err := pb.FindGaps(start.SequenceNumber, end.SequenceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("finding gaps: %w", err)
+	}
+
+	runStarts := make([]segment.Metadata, 0, len(gaps)+1)
+	runStarts = append(runStarts, start)
+	for _, gap := range gaps {
+		runStarts = append(runStarts, gap.After)
+	}
+
+	runs := make([]mpd.SegmentRun, 0, len(runStarts))
+	for i, first := range runStarts {
+		next := end.SequenceNumber + 1
+		if i+1 < len(runStarts) {
+			next = runStarts[i+1].SequenceNumber
+		}
+		runs = append(runs, mpd.SegmentRun{
+			Offset: first.Time().Sub(start.Time()),
+			Count:  next - first.SequenceNumber,
+		})
+	}
+
+	return runs, nil
+}
+
+// ComposeDynamic composes a dynamic MPD starting from the moment.
+//
+// Without a window, the moment is presented as the live edge. With a positive
+// window, the presentation follows the actual stream timeline instead: it is
+// available from the walltime the moment's segment would have without gaps
+// before the head, so the live edge is at the head and players can seek back
+// within the window.
 func ComposeDynamic(
 	pb playback.Playbacker,
 	moment *playback.RewindMoment,
+	head segment.Metadata,
 	baseURL string,
+	window time.Duration,
 	runner exec.Runner,
 ) ([]byte, error) {
 	startNumber := moment.Metadata.SequenceNumber
@@ -56,7 +120,7 @@ func ComposeDynamic(
 		return nil, fmt.Errorf("extracting pts: %w", err)
 	}
 
-	out, err := mpd.ComposeDynamic(mpd.DynamicOptions{
+	opts := mpd.DynamicOptions{
 		CommonOptions: mpd.CommonOptions{
 			BaseURL:         baseURL,
 			StartNumber:     startNumber,
@@ -64,7 +128,18 @@ func ComposeDynamic(
 			PTS:             pts,
 		},
 		AvailabilityStartTime: time.Now(),
-	}, pb.Info())
+	}
+	if window > 0 {
+		// Players number segments by the wall clock since availability start,
+		// so anchor it to the head to keep the live edge right after gaps
+		behind := time.Duration(head.SequenceNumber-startNumber) * pb.Info().SegmentDuration
+		opts.AvailabilityStartTime = head.IngestionWalltime.Add(-behind)
+		opts.TimeShiftBufferDepth = window
+		opts.MinimumUpdatePeriod = windowUpdatePeriod
+		opts.SuggestedPresentationDelay = windowPresentationDelay * pb.Info().SegmentDuration
+	}
+
+	out, err := mpd.ComposeDynamic(opts, pb.Info())
 	if err != nil {
 		return nil, fmt.Errorf("composing mpd: %w", err)
 	}
@@ -0,0 +1,117 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/mpd"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func TestLocateTimelineRuns(t *testing.T) {
+	t.Parallel()
+
+	// shift moves segments starting from sq forward in time
+	shift := func(data testutil.MetadataMap, sq int, d time.Duration) {
+		for i := sq; i < len(data); i++ {
+			m := data[i]
+			m.IngestionWalltime = m.IngestionWalltime.Add(d)
+			data[i] = m
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		gaps     map[int]time.Duration
+		expected []mpd.SegmentRun
+	}{
+		{
+			name:     "no gaps",
+			expected: []mpd.SegmentRun{{Offset: 0, Count: 100}},
+		},
+		{
+			name: "jitter below threshold",
+			gaps: map[int]time.Duration{50: 100 * time.Millisecond},
+			expected: []mpd.SegmentRun{
+				{Offset: 0, Count: 100},
+			},
+		},
+		{
+			name: "one gap",
+			gaps: map[int]time.Duration{37: 10 * time.Second},
+			expected: []mpd.SegmentRun{
+				{Offset: 0, Count: 37},
+				{Offset: 84 * time.Second, Count: 63},
+			},
+		},
+		{
+			name: "gap caught up later",
+			gaps: map[int]time.Duration{
+				37: 10 * time.Second,
+				60: -10 * time.Second,
+			},
+			expected: []mpd.SegmentRun{
+				{Offset: 0, Count: 37},
+				{Offset: 84 * time.Second, Count: 63},
+			},
+		},
+		{
+			name: "two gaps",
+			gaps: map[int]time.Duration{
+				10: 10 * time.Second,
+				90: time.Minute,
+			},
+			expected: []mpd.SegmentRun{
+				{Offset: 0, Count: 10},
+				{Offset: 30 * time.Second, Count: 80},
+				{Offset: 250 * time.Second, Count: 10},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeMetadata := testutil.GenerateFakeSegmentMetadata(100, 2*time.Second)
+			for sq, d := range tc.gaps {
+				shift(fakeMetadata, sq, d)
+			}
+			pb := newFakePlayback(fakeMetadata)
+
+			first, last := fakeMetadata[0], fakeMetadata[99]
+			interval := &playback.RewindInterval{
+				Start: playback.NewRewindMoment(first.Time(), first, false, false),
+				End:   playback.NewRewindMoment(last.EndTime(), last, true, false),
+			}
+
+			actual, err := actions.LocateTimelineRuns(pb, interval)
+			require.NoError(t, err)
+
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Error(testutil.PrintWantGot(diff))
+			}
+		})
+	}
+}
+
+// countingPlayback counts metadata requests of the wrapped fake playback.
+type countingPlayback struct {
+	*fakePlayback
+	requests int
+}
+
+func (pb *countingPlayback) FetchSegmentMetadata(
+	itag string,
+	sq playback.SequenceNumber,
+	options ...playback.FetchOption,
+) (*segment.Metadata, error) {
+	pb.requests++
+	return pb.fakePlayback.FetchSegmentMetadata(itag, sq, options...)
+}
@@ -0,0 +1,147 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/xymaxim/ypb/internal/exec"
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+// Track is a media stream downloaded to a file.
+type Track struct {
+	Itag string `json:"itag"`
+	Path string `json:"path"`
+	// Completed is the number of segments already downloaded, and Size is the
+	// file size after them. They allow to resume downloading.
+	Completed int   `json:"completed"`
+	Size      int64 `json:"size"`
+}
+
+// DownloadTracks downloads segments of an interval for all tracks
+// concurrently. Segments of each track are concatenated into its file, since
+// they are self-initializing fMP4 fragments. Tracks with completed segments
+// are resumed, and their progress is updated after each segment.
+//
+// The onProgress function, if not nil, is called after each downloaded
+// segment with the number of downloaded and total segments of all tracks. It
+// is called sequentially, so tracks can be safely read in it.
+func DownloadTracks(
+	pb playback.Playbacker,
+	interval *playback.RewindInterval,
+	tracks []Track,
+	onProgress func(done, total int),
+) error {
+	start := interval.Start.Metadata.SequenceNumber
+	end := interval.End.Metadata.SequenceNumber
+
+	var mu sync.Mutex
+	done, total := 0, (end-start+1)*len(tracks)
+	for _, track := range tracks {
+		done += track.Completed
+	}
+
+	errs := make([]error, len(tracks))
+	var wg sync.WaitGroup
+	for i := range tracks {
+		wg.Go(func() {
+			errs[i] = downloadTrack(pb, tracks[i], start, end, func(size int64) {
+				mu.Lock()
+				defer mu.Unlock()
+				tracks[i].Completed++
+				tracks[i].Size += size
+				done++
+				if onProgress != nil {
+					onProgress(done, total)
+				}
+			})
+		})
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func downloadTrack(
+	pb playback.Playbacker,
+	track Track,
+	start, end playback.SequenceNumber,
+	onSegment func(size int64),
+) error {
+	from := start + track.Completed
+	slog.Info("downloading track", "itag", track.Itag, "start", from, "end", end)
+
+	f, err := os.OpenFile(track.Path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening track file: %w", err)
+	}
+	defer f.Close()
+
+	// Drop a partially written segment, if any
+	if err := f.Truncate(track.Size); err != nil {
+		return fmt.Errorf("truncating track file: %w", err)
+	}
+	if _, err := f.Seek(track.Size, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking track file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for sq := from; sq <= end; sq++ {
+		buf.Reset()
+		if err := pb.StreamSegment(track.Itag, sq, &buf); err != nil {
+			return fmt.Errorf("downloading segment, itag=%s, sq=%d: %w", track.Itag, sq, err)
+		}
+		size, err := buf.WriteTo(f)
+		if err != nil {
+			return fmt.Errorf("writing segment, itag=%s, sq=%d: %w", track.Itag, sq, err)
+		}
+		onSegment(size)
+	}
+
+	return f.Close()
+}
+
+// MuxTracks muxes tracks into a single output file without re-encoding. The
+// metadata, if not empty, is written to the output.
+func MuxTracks(
+	tracks []Track,
+	metadata *MediaMetadata,
+	outputPath string,
+	runner exec.Runner,
+) error {
+	args := []string{"-hide_banner", "-y"}
+	for _, track := range tracks {
+		args = append(args, "-i", track.Path)
+	}
+	if !metadata.IsEmpty() {
+		metadataPath, err := writeFFMetadataFile(metadata)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(metadataPath)
+		args = append(args,
+			"-i", metadataPath,
+			"-map_metadata", strconv.Itoa(len(tracks)),
+			"-map_chapters", strconv.Itoa(len(tracks)),
+		)
+		args = append(args, metadataMuxerArgs(outputPath)...)
+	}
+	for i := range tracks {
+		args = append(args, "-map", strconv.Itoa(i))
+	}
+	args = append(args, "-c", "copy", outputPath)
+
+	result, err := runner.RunWith(context.Background(), []exec.Option{exec.WithQuiet()}, args...)
+	if err != nil {
+		return fmt.Errorf("muxing tracks: %w (stderr: %s)", err, result.Stderr)
+	}
+
+	return nil
+}
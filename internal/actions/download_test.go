@@ -0,0 +1,105 @@
+package actions_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+type segmentPlayback struct {
+	*fakePlayback
+}
+
+func (pb *segmentPlayback) StreamSegment(
+	itag string,
+	sq playback.SequenceNumber,
+	w io.Writer,
+) error {
+	_, err := fmt.Fprintf(w, "[%s:%d]", itag, sq)
+	return err
+}
+
+func TestDownloadTracks(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(10, 2*time.Second)
+	pb := &segmentPlayback{newFakePlayback(fakeMetadata)}
+
+	first, last := fakeMetadata[2], fakeMetadata[4]
+	interval := &playback.RewindInterval{
+		Start: playback.NewRewindMoment(first.Time(), first, false, false),
+		End:   playback.NewRewindMoment(last.EndTime(), last, true, false),
+	}
+
+	dir := t.TempDir()
+	tracks := []actions.Track{
+		{Itag: "137", Path: filepath.Join(dir, "video.part")},
+		{Itag: "140", Path: filepath.Join(dir, "audio.part")},
+	}
+
+	var mu sync.Mutex
+	progress := []int{}
+	err := actions.DownloadTracks(pb, interval, tracks, func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, 6, total)
+		progress = append(progress, done)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, progress)
+
+	video, err := os.ReadFile(tracks[0].Path)
+	require.NoError(t, err)
+	assert.Equal(t, "[137:2][137:3][137:4]", string(video))
+
+	audio, err := os.ReadFile(tracks[1].Path)
+	require.NoError(t, err)
+	assert.Equal(t, "[140:2][140:3][140:4]", string(audio))
+}
+
+func TestDownloadTracks_Resume(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(10, 2*time.Second)
+	pb := &segmentPlayback{newFakePlayback(fakeMetadata)}
+
+	first, last := fakeMetadata[2], fakeMetadata[4]
+	interval := &playback.RewindInterval{
+		Start: playback.NewRewindMoment(first.Time(), first, false, false),
+		End:   playback.NewRewindMoment(last.EndTime(), last, true, false),
+	}
+
+	// One segment is completed, and the next one is partially written
+	path := filepath.Join(t.TempDir(), "video.part")
+	require.NoError(t, os.WriteFile(path, []byte("[137:2][137:"), 0o600))
+
+	tracks := []actions.Track{
+		{Itag: "137", Path: path, Completed: 1, Size: int64(len("[137:2]"))},
+	}
+
+	progress := []int{}
+	err := actions.DownloadTracks(pb, interval, tracks, func(done, _ int) {
+		progress = append(progress, done)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2, 3}, progress)
+	assert.Equal(t, 3, tracks[0].Completed)
+
+	video, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[137:2][137:3][137:4]", string(video))
+	assert.Equal(t, int64(len(video)), tracks[0].Size)
+}
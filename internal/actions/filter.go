@@ -0,0 +1,32 @@
+package actions
+
+import (
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/info"
+)
+
+// filteredPlayback exposes a filtered copy of the info of a playback. Other
+// methods, including ProbeItag, work on the whole playback.
+type filteredPlayback struct {
+	playback.Playbacker
+	info info.VideoInformation
+}
+
+func (pb *filteredPlayback) Info() info.VideoInformation {
+	return pb.info
+}
+
+// FilterStreams returns a playback whose info lists only the streams matching
+// the filter, so that manifests and downloads include just them.
+func FilterStreams(pb playback.Playbacker, filter info.StreamFilter) (playback.Playbacker, error) {
+	if filter.IsZero() {
+		return pb, nil
+	}
+
+	filtered, err := filter.Apply(pb.Info())
+	if err != nil {
+		return nil, err
+	}
+
+	return &filteredPlayback{Playbacker: pb, info: filtered}, nil
+}
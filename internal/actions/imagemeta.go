@@ -0,0 +1,236 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader   = []byte("Exif\x00\x00")
+)
+
+const (
+	jpegMarkerSOI  = 0xd8
+	jpegMarkerAPP0 = 0xe0
+	jpegMarkerAPP1 = 0xe1
+
+	exifTypeASCII           = 2
+	exifTagImageDescription = 0x010e
+	exifTagSoftware         = 0x0131
+	exifTagDateTime         = 0x0132
+	exifTagArtist           = 0x013b
+	exifDateTimeLayout      = "2006:01:02 15:04:05"
+)
+
+// WriteImageMetadata embeds the provenance into a PNG (as text chunks) or JPEG
+// (as EXIF) image in place. Images of other formats are left as is.
+func WriteImageMetadata(path string, provenance *Provenance) error {
+	b, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("reading image: %w", err)
+	}
+
+	description, err := json.Marshal(provenance)
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+
+	software := "ypb " + provenance.Version
+	createdAt := provenance.ActualStart.UTC().Format(exifDateTimeLayout)
+
+	var out []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		out, err = insertPNGText(b, [][2]string{
+			{"Title", provenance.Title},
+			{"Author", provenance.Channel},
+			{"Source", provenance.Tags()["comment"]},
+			{"Creation Time", createdAt},
+			{"Software", software},
+			{"Description", string(description)},
+		})
+	case ".jpg", ".jpeg":
+		// EXIF strings are ASCII, so other characters are escaped in the
+		// description, and left out of the other tags
+		tags := map[uint16]string{
+			exifTagImageDescription: escapeNonASCII(description),
+			exifTagSoftware:         software,
+			exifTagDateTime:         createdAt,
+		}
+		if isASCII(provenance.Channel) {
+			tags[exifTagArtist] = provenance.Channel
+		}
+		out, err = insertJPEGExif(b, tags)
+	default:
+		slog.Debug("image format does not support metadata", "path", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("embedding metadata: %w", err)
+	}
+
+	if err := os.WriteFile(path, out, 0o644); err != nil { // #nosec G306
+		return fmt.Errorf("writing image: %w", err)
+	}
+
+	return nil
+}
+
+// insertPNGText inserts text chunks with the keywords and texts right after
+// the header chunk. Texts of ASCII characters are stored in tEXt chunks, and
+// other ones in UTF-8 iTXt chunks.
+func insertPNGText(b []byte, texts [][2]string) ([]byte, error) {
+	// Signature, then the header chunk: length, type, 13 bytes of data, CRC
+	headerEnd := len(pngSignature) + 4 + 4 + 13 + 4
+	if len(b) < headerEnd || !bytes.HasPrefix(b, pngSignature) ||
+		string(b[len(pngSignature)+4:len(pngSignature)+8]) != "IHDR" {
+		return nil, errors.New("not a PNG image")
+	}
+
+	var chunks bytes.Buffer
+	for _, text := range texts {
+		keyword, value := text[0], text[1]
+		if value == "" {
+			continue
+		}
+		if isASCII(value) {
+			writePNGChunk(&chunks, "tEXt", []byte(keyword+"\x00"+value))
+		} else {
+			// Keyword, compression flag and method, language tag, and
+			// translated keyword
+			writePNGChunk(&chunks, "iTXt", []byte(keyword+"\x00\x00\x00\x00\x00"+value))
+		}
+	}
+
+	out := make([]byte, 0, len(b)+chunks.Len())
+	out = append(out, b[:headerEnd]...)
+	out = append(out, chunks.Bytes()...)
+	out = append(out, b[headerEnd:]...)
+
+	return out, nil
+}
+
+func writePNGChunk(w *bytes.Buffer, chunkType string, data []byte) {
+	_ = binary.Write(w, binary.BigEndian, uint32(len(data)))
+	w.WriteString(chunkType)
+	w.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	_ = binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// insertJPEGExif inserts an EXIF segment with the ASCII tags of the primary
+// image. It follows the JFIF segment, if any, or the start of the image.
+func insertJPEGExif(b []byte, tags map[uint16]string) ([]byte, error) {
+	if len(b) < 4 || b[0] != 0xff || b[1] != jpegMarkerSOI {
+		return nil, errors.New("not a JPEG image")
+	}
+
+	at := 2
+	if b[2] == 0xff && b[3] == jpegMarkerAPP0 && len(b) >= 6 {
+		at += 2 + int(binary.BigEndian.Uint16(b[4:6]))
+		if at > len(b) {
+			return nil, errors.New("truncated JFIF segment")
+		}
+	}
+
+	payload := append(append([]byte{}, exifHeader...), buildTIFF(tags)...)
+	if len(payload)+2 > 0xffff {
+		return nil, errors.New("EXIF data is too large")
+	}
+
+	var segment bytes.Buffer
+	segment.Write([]byte{0xff, jpegMarkerAPP1})
+	_ = binary.Write(&segment, binary.BigEndian, uint16(len(payload)+2))
+	segment.Write(payload)
+
+	out := make([]byte, 0, len(b)+segment.Len())
+	out = append(out, b[:at]...)
+	out = append(out, segment.Bytes()...)
+	out = append(out, b[at:]...)
+
+	return out, nil
+}
+
+// buildTIFF builds big-endian TIFF data with a single IFD of ASCII tags.
+func buildTIFF(tags map[uint16]string) []byte {
+	ids := make([]uint16, 0, len(tags))
+	for id, value := range tags {
+		if value != "" {
+			ids = append(ids, id)
+		}
+	}
+	// Entries of an IFD are sorted by tag
+	slices.Sort(ids)
+
+	const headerSize, entrySize = 8, 12
+	dataOffset := headerSize + 2 + len(ids)*entrySize + 4
+
+	var ifd, data bytes.Buffer
+	_ = binary.Write(&ifd, binary.BigEndian, uint16(len(ids)))
+	for _, id := range ids {
+		value := append([]byte(tags[id]), 0)
+		_ = binary.Write(&ifd, binary.BigEndian, id)
+		_ = binary.Write(&ifd, binary.BigEndian, uint16(exifTypeASCII))
+		_ = binary.Write(&ifd, binary.BigEndian, uint32(len(value)))
+		if len(value) <= 4 {
+			ifd.Write(append(value, make([]byte, 4-len(value))...))
+			continue
+		}
+		_ = binary.Write(&ifd, binary.BigEndian, uint32(dataOffset+data.Len()))
+		data.Write(value)
+		// Values start at word boundaries
+		if data.Len()%2 != 0 {
+			data.WriteByte(0)
+		}
+	}
+	_ = binary.Write(&ifd, binary.BigEndian, uint32(0))
+
+	var out bytes.Buffer
+	out.WriteString("MM\x00\x2a")
+	_ = binary.Write(&out, binary.BigEndian, uint32(headerSize))
+	out.Write(ifd.Bytes())
+	out.Write(data.Bytes())
+
+	return out.Bytes()
+}
+
+// escapeNonASCII escapes non-ASCII characters of JSON data as \uXXXX
+// sequences, which decode to the same text.
+func escapeNonASCII(data []byte) string {
+	var sb strings.Builder
+	for _, r := range string(data) {
+		switch {
+		case r <= unicode.MaxASCII:
+			sb.WriteRune(r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&sb, `\u%04x\u%04x`, r1, r2)
+		default:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		}
+	}
+	return sb.String()
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
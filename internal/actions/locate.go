@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/xymaxim/ypb/internal/input"
@@ -77,11 +79,33 @@ type LocateOutputContext struct {
 // the 'now' keyword: in strict mode (downloads and capture), it is set to the
 // app start-up time; in non-strict mode (serve), it is nil and 'now' falls back
 // to the end of the most recent segment.
+//
+// A context is safe to share between goroutines locating moments at once.
+// Keywords are resolved once per start or end side, and the earliest segment
+// is probed once per context. Callers resolving the same side wait for the
+// resolution in flight, while other sides are resolved meanwhile.
 type LocateContext struct {
-	Head         segment.Metadata
-	Reference    segment.Metadata
-	PinnedTime   *time.Time
-	PinnedMoment *playback.RewindMoment
+	Head       segment.Metadata
+	Reference  segment.Metadata
+	PinnedTime *time.Time
+
+	// mu guards keywords and sides, and is not held while resolving.
+	mu sync.Mutex
+	// keywords caches resolved keyword moments.
+	keywords map[keywordSide]*playback.RewindMoment
+	// sides serializes resolutions of each side.
+	sides map[keywordSide]*sync.Mutex
+
+	// earliestMu guards earliest, and is held while probing for it.
+	earliestMu sync.Mutex
+	// earliest caches the earliest available segment.
+	earliest *segment.Metadata
+}
+
+// keywordSide identifies a keyword resolved as a start or an end moment.
+type keywordSide struct {
+	keyword input.MomentKeyword
+	isEnd   bool
 }
 
 // NewLocateContext creates a new LocateContext.
@@ -159,6 +183,79 @@ func LocateInterval(
 	return interval, context, nil
 }
 
+// LocateMoments locates many target times in one pass.
+//
+// Targets are processed in chronological order, and each located moment serves
+// as the reference for the next one. Segments probed while searching for a
+// target are kept by the playback, so the search for the next target starts
+// from the bracket left by the previous one. Targets falling into the
+// previously located segment are resolved without any requests. The returned
+// moments are in the input order.
+func LocateMoments(
+	pb playback.Playbacker,
+	times []time.Time,
+	ctx *LocateContext,
+) ([]*playback.RewindMoment, error) {
+	slog.Info("locating moments", "count", len(times))
+
+	order := make([]int, len(times))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return times[a].Compare(times[b])
+	})
+
+	moments := make([]*playback.RewindMoment, len(times))
+	locator := newMomentLocator(pb, ctx)
+	for _, i := range order {
+		moment, err := locator.locate(times[i])
+		if err != nil {
+			return nil, fmt.Errorf("target %d at %s: %w", i, times[i], err)
+		}
+		moments[i] = moment
+	}
+
+	return moments, nil
+}
+
+// momentLocator locates target times one by one, starting each search from
+// the moment located before.
+type momentLocator struct {
+	pb        playback.Playbacker
+	ctx       *LocateContext
+	reference segment.Metadata
+	previous  *playback.RewindMoment
+}
+
+func newMomentLocator(pb playback.Playbacker, ctx *LocateContext) *momentLocator {
+	return &momentLocator{pb: pb, ctx: ctx, reference: ctx.Reference}
+}
+
+// locate locates the target time t as a start moment.
+func (l *momentLocator) locate(t time.Time) (*playback.RewindMoment, error) {
+	if t.After(l.ctx.Head.EndTime()) {
+		return nil, fmt.Errorf("time %v is after current moment", t)
+	}
+
+	if l.previous != nil && !l.previous.InGap && segmentContains(l.previous.Metadata, t) {
+		return playback.NewRewindMoment(t, l.previous.Metadata, false, false), nil
+	}
+
+	moment, err := locateTime(l.pb, t, l.reference, false)
+	if err != nil {
+		return nil, fmt.Errorf("locating moment: %w", err)
+	}
+	l.previous, l.reference = moment, moment.Metadata
+
+	return moment, nil
+}
+
+// segmentContains reports whether t falls within [start, end) of the segment m.
+func segmentContains(m segment.Metadata, t time.Time) bool {
+	return !t.Before(m.Time()) && t.Before(m.EndTime())
+}
+
 func fetchHeadMetadata(pb playback.Playbacker) (*segment.Metadata, error) {
 	sq, err := pb.RequestHeadSeqNum()
 	if err != nil {
@@ -246,7 +343,7 @@ func locateWithAbsoluteStart(
 	// Handle duration end
 	if duration, ok := end.(time.Duration); ok {
 		endTime := startMoment.TargetTime.Add(duration)
-		endMoment, err := pb.LocateMoment(endTime, ctx.Reference, true)
+		endMoment, err := locateTime(pb, endTime, ctx.Reference, true)
 		if err != nil {
 			return nil, fmt.Errorf("locating end moment: %w", err)
 		}
@@ -272,7 +369,7 @@ func locateWithDurationStart(
 			return nil, NewResolveMomentError(end, true, err)
 		}
 		startTime := endMoment.TargetTime.Add(-startDuration)
-		startMoment, err := pb.LocateMoment(startTime, ctx.Reference, false)
+		startMoment, err := locateTime(pb, startTime, ctx.Reference, false)
 		if err != nil {
 			return nil, fmt.Errorf("locating start moment: %w", err)
 		}
@@ -312,13 +409,24 @@ func resolveTime(
 	if t.After(ctx.Head.EndTime()) {
 		return nil, fmt.Errorf("time %v is after current moment", t)
 	}
-	moment, err := pb.LocateMoment(t, ctx.Reference, isEnd)
+	moment, err := locateTime(pb, t, ctx.Reference, isEnd)
 	if err != nil {
 		return nil, fmt.Errorf("locating moment at %v: %w", t, err)
 	}
 	return moment, nil
 }
 
+// locateTime locates the time t starting from the segment nearest to it: either
+// the reference or one of the segments seen before.
+func locateTime(
+	pb playback.Playbacker,
+	t time.Time,
+	reference segment.Metadata,
+	isEnd bool,
+) (*playback.RewindMoment, error) {
+	return pb.LocateMoment(t, pb.NearestReference(t, reference), isEnd)
+}
+
 // resolveSequenceNumber resolves the sequence number sq into a RewindMoment.
 func resolveSequenceNumber(
 	pb playback.Playbacker,
@@ -347,19 +455,29 @@ func resolveSequenceNumber(
 	return playback.NewRewindMoment(targetTime, *metadata, isEnd, false), nil
 }
 
-// resolveKeywordMoment resolves a keyword into a RewindMoment.
+// resolveKeyword resolves a keyword into a RewindMoment. Resolved moments
+// are cached in the context per side, since a keyword may resolve to
+// different segments as a start and as an end.
 func resolveKeyword(
 	pb playback.Playbacker,
 	keyword input.MomentKeyword,
 	ctx *LocateContext,
 	isEnd bool,
 ) (*playback.RewindMoment, error) {
+	side := keywordSide{keyword: keyword, isEnd: isEnd}
+
+	// Callers of the same side wait here and find the moment resolved
+	sideMu := ctx.sideMutex(side)
+	sideMu.Lock()
+	defer sideMu.Unlock()
+
+	if moment, ok := ctx.keyword(side); ok {
+		return moment, nil
+	}
+
+	var moment *playback.RewindMoment
 	switch keyword {
 	case input.NowKeyword:
-		if ctx.PinnedMoment != nil {
-			return ctx.PinnedMoment, nil
-		}
-
 		if ctx.PinnedTime != nil {
 			m, err := resolveTime(pb, *ctx.PinnedTime, ctx, isEnd)
 			if err != nil {
@@ -369,9 +487,9 @@ func resolveKeyword(
 					err,
 				)
 			}
-			ctx.PinnedMoment = m
+			moment = m
 		} else {
-			ctx.PinnedMoment = playback.NewRewindMoment(
+			moment = playback.NewRewindMoment(
 				ctx.Head.EndTime(),
 				ctx.Head,
 				isEnd,
@@ -381,15 +499,126 @@ func resolveKeyword(
 
 		slog.Debug(
 			"resolved now keyword",
-			slog.Int("sq", ctx.PinnedMoment.Metadata.SequenceNumber),
-			slog.Time("time", ctx.PinnedMoment.TargetTime),
+			slog.Int("sq", moment.Metadata.SequenceNumber),
+			slog.Time("time", moment.TargetTime),
 		)
 
-		return ctx.PinnedMoment, nil
+	case input.EarliestKeyword:
+		earliest, err := ctx.earliestSegment(pb)
+		if err != nil {
+			return nil, err
+		}
+		moment = playback.NewRewindMoment(earliest.Time(), *earliest, isEnd, false)
+
+		slog.Debug(
+			"resolved earliest keyword",
+			slog.Int("sq", moment.Metadata.SequenceNumber),
+			slog.Time("time", moment.TargetTime),
+		)
 
 	default:
 		return nil, fmt.Errorf("unknown keyword: '%s'", keyword)
 	}
+
+	ctx.mu.Lock()
+	if ctx.keywords == nil {
+		ctx.keywords = make(map[keywordSide]*playback.RewindMoment)
+	}
+	ctx.keywords[side] = moment
+	ctx.mu.Unlock()
+
+	return moment, nil
+}
+
+// sideMutex returns the mutex serializing resolutions of the side.
+func (ctx *LocateContext) sideMutex(side keywordSide) *sync.Mutex {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if ctx.sides == nil {
+		ctx.sides = make(map[keywordSide]*sync.Mutex)
+	}
+	sideMu, ok := ctx.sides[side]
+	if !ok {
+		sideMu = &sync.Mutex{}
+		ctx.sides[side] = sideMu
+	}
+
+	return sideMu
+}
+
+// keyword returns the cached moment of the side.
+func (ctx *LocateContext) keyword(side keywordSide) (*playback.RewindMoment, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	moment, ok := ctx.keywords[side]
+	return moment, ok
+}
+
+// earliestSegment returns the earliest available segment, probing for it once
+// per context.
+func (ctx *LocateContext) earliestSegment(pb playback.Playbacker) (*segment.Metadata, error) {
+	ctx.earliestMu.Lock()
+	defer ctx.earliestMu.Unlock()
+
+	if ctx.earliest == nil {
+		metadata, err := locateEarliestSegment(pb, ctx.Head)
+		if err != nil {
+			return nil, fmt.Errorf("locating earliest segment: %w", err)
+		}
+		ctx.earliest = metadata
+	}
+
+	return ctx.earliest, nil
+}
+
+// locateEarliestSegment finds the oldest segment that is still available. It
+// probes back from the head with exponentially growing steps until a segment
+// stops returning, then bisects between the last available and the first
+// unavailable segments. Errors other than unavailable segments are returned,
+// so a failed request is not taken for the end of the rewind window.
+func locateEarliestSegment(
+	pb playback.Playbacker,
+	head segment.Metadata,
+) (*segment.Metadata, error) {
+	available := head
+	unavailable := -1
+
+	// Step 1: Exponential probing back from the head
+	for step := 1; ; step *= 2 {
+		sq := max(head.SequenceNumber-step, 0)
+		metadata, err := pb.FetchSegmentMetadata(pb.ProbeItag(), sq, playback.AsProbe())
+		if err != nil {
+			if !errors.Is(err, playback.ErrSegmentUnavailable) {
+				return nil, err
+			}
+			slog.Debug("segment is unavailable", slog.Int("sq", sq), slog.Any("error", err))
+			unavailable = sq
+			break
+		}
+		available = *metadata
+		if sq == 0 {
+			return &available, nil
+		}
+	}
+
+	// Step 2: Binary search between unavailable and available segments
+	for available.SequenceNumber-unavailable > 1 {
+		sq := unavailable + (available.SequenceNumber-unavailable)/2
+		metadata, err := pb.FetchSegmentMetadata(pb.ProbeItag(), sq, playback.AsProbe())
+		if err != nil {
+			if !errors.Is(err, playback.ErrSegmentUnavailable) {
+				return nil, err
+			}
+			slog.Debug("segment is unavailable", slog.Int("sq", sq), slog.Any("error", err))
+			unavailable = sq
+			continue
+		}
+		available = *metadata
+	}
+
+	return &available, nil
 }
 
 // resolveExpression evaluates the moment expression expr into a RewindMoment.
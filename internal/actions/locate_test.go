@@ -1,18 +1,24 @@
 package actions_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/xymaxim/ypb/internal/actions"
 	"github.com/xymaxim/ypb/internal/input"
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/cache"
 	"github.com/xymaxim/ypb/internal/playback/info"
 	"github.com/xymaxim/ypb/internal/playback/segment"
 	"github.com/xymaxim/ypb/internal/testutil"
@@ -47,14 +53,40 @@ func (pb *fakePlayback) RequestHeadSeqNum() (int, error) {
 func (pb *fakePlayback) FetchSegmentMetadata(
 	_ string,
 	sq playback.SequenceNumber,
+	_ ...playback.FetchOption,
 ) (*segment.Metadata, error) {
 	m, ok := pb.fakeMetadata[sq]
 	if !ok {
-		return nil, fmt.Errorf("fetching segment metadata, sq=%d", sq)
+		return nil, fmt.Errorf(
+			"fetching segment metadata, sq=%d: %w",
+			sq,
+			playback.ErrSegmentUnavailable,
+		)
 	}
 	return &m, nil
 }
 
+func (pb *fakePlayback) FindGaps(
+	start, end playback.SequenceNumber,
+) ([]playback.Gap, error) {
+	timeline := make([]segment.Metadata, 0, end-start+1)
+	for sq := start; sq <= end; sq++ {
+		m, err := pb.FetchSegmentMetadata("", sq)
+		if err != nil {
+			return nil, err
+		}
+		timeline = append(timeline, *m)
+	}
+	return playback.DetectGaps(timeline), nil
+}
+
+func (pb *fakePlayback) NearestReference(
+	_ time.Time,
+	fallback segment.Metadata,
+) segment.Metadata {
+	return fallback
+}
+
 // LocateMoment returns the rewind moment corresponds the target time. For tests
 // only. For example, it does not handle timeline gaps.
 //
@@ -224,6 +256,304 @@ func TestLocateMoment_BadMomentType(t *testing.T) {
 	}
 }
 
+func TestLocateMoment_Earliest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		unavailable int
+	}{
+		{
+			name:        "all segments available",
+			unavailable: 0,
+		},
+		{
+			name:        "first segment unavailable",
+			unavailable: 1,
+		},
+		{
+			name:        "several segments unavailable",
+			unavailable: 7,
+		},
+		{
+			name:        "only head available",
+			unavailable: 19,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+			for sq := range tc.unavailable {
+				delete(fakeMetadata, sq)
+			}
+			earliest := fakeMetadata[tc.unavailable]
+
+			pb := newFakePlayback(fakeMetadata)
+			head := fakeMetadata[19]
+			ctx := &actions.LocateContext{Head: head, Reference: head}
+
+			moment, err := actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+			require.NoError(t, err)
+
+			expected := &playback.RewindMoment{
+				Metadata:   earliest,
+				ActualTime: earliest.Time(),
+				TargetTime: earliest.Time(),
+				InGap:      false,
+			}
+			if diff := cmp.Diff(expected, moment); diff != "" {
+				t.Fatalf("Mismatch (- expected, + actual):\n%s", diff)
+			}
+			again, err := actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+			require.NoError(t, err)
+			require.Same(t, moment, again)
+		})
+	}
+}
+
+type failingPlayback struct {
+	*fakePlayback
+	failingSq int
+}
+
+func (pb *failingPlayback) FetchSegmentMetadata(
+	itag string,
+	sq playback.SequenceNumber,
+	_ ...playback.FetchOption,
+) (*segment.Metadata, error) {
+	if sq == pb.failingSq {
+		return nil, errors.New("got unexpected status: 503 Service Unavailable")
+	}
+	return pb.fakePlayback.FetchSegmentMetadata(itag, sq)
+}
+
+func TestLocateMoment_EarliestFailedRequest(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &failingPlayback{fakePlayback: newFakePlayback(fakeMetadata), failingSq: 11}
+	head := fakeMetadata[19]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	_, err := actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, playback.ErrSegmentUnavailable)
+
+	// The failure is not cached
+	pb.failingSq = -1
+	moment, err := actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+	require.NoError(t, err)
+	require.Equal(t, fakeMetadata[0], moment.Metadata)
+}
+
+func TestLocateMoment_KeywordSides(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &countingPlayback{fakePlayback: newFakePlayback(fakeMetadata)}
+	head := fakeMetadata[19]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	now, err := actions.LocateMoment(pb, input.NowKeyword, ctx)
+	require.NoError(t, err)
+	require.Equal(t, head.Time(), now.ActualTime)
+
+	earliest, err := actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+	require.NoError(t, err)
+	require.Equal(t, fakeMetadata[0].IngestionWalltime, earliest.ActualTime)
+	requests := pb.requests
+
+	interval, _, err := actions.LocateInterval(pb, input.EarliestKeyword, input.NowKeyword, ctx)
+	require.NoError(t, err)
+	require.Same(t, earliest, interval.Start)
+	require.Equal(t, head.EndTime(), interval.End.ActualTime)
+	require.Equal(t, requests, pb.requests, "earliest segment should be probed once")
+
+	end, err := actions.LocateMoment(pb, input.NowKeyword, ctx)
+	require.NoError(t, err)
+	require.Same(t, now, end, "start side should be cached separately")
+}
+
+func TestLocateMoment_KeywordsConcurrently(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := newFakePlayback(fakeMetadata)
+	head := fakeMetadata[19]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	const workers = 16
+	earliest := make([]*playback.RewindMoment, workers)
+	now := make([]*playback.RewindMoment, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Go(func() {
+			var err error
+			earliest[i], err = actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+			require.NoError(t, err)
+			now[i], err = actions.LocateMoment(pb, input.NowKeyword, ctx)
+			require.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	for i := range workers {
+		require.Same(t, earliest[0], earliest[i], "earliest should be resolved once")
+		require.Same(t, now[0], now[i], "now should be resolved once")
+	}
+}
+
+// blockingPlayback blocks metadata requests until released.
+type blockingPlayback struct {
+	*fakePlayback
+	probing chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (pb *blockingPlayback) FetchSegmentMetadata(
+	itag string,
+	sq playback.SequenceNumber,
+	options ...playback.FetchOption,
+) (*segment.Metadata, error) {
+	pb.once.Do(func() { close(pb.probing) })
+	<-pb.release
+	return pb.fakePlayback.FetchSegmentMetadata(itag, sq, options...)
+}
+
+func TestLocateMoment_KeywordsNotBlocked(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &blockingPlayback{
+		fakePlayback: newFakePlayback(fakeMetadata),
+		probing:      make(chan struct{}),
+		release:      make(chan struct{}),
+	}
+	head := fakeMetadata[19]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	done := make(chan error)
+	go func() {
+		_, err := actions.LocateMoment(pb, input.EarliestKeyword, ctx)
+		done <- err
+	}()
+	<-pb.probing
+
+	// Other keywords are resolved while the earliest one is being probed
+	now, err := actions.LocateMoment(pb, input.NowKeyword, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, head, now.Metadata)
+
+	close(pb.release)
+	require.NoError(t, <-done)
+}
+
+func TestLocateMoments(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(5, 2*time.Second)
+	pb := newFakePlayback(fakeMetadata)
+	head := fakeMetadata[len(fakeMetadata)-1]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	// Unordered targets, with two of them falling into the same segment
+	times := []time.Time{
+		time.Date(2026, 1, 2, 10, 20, 37, 0, time.UTC),
+		time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC),
+		time.Date(2026, 1, 2, 10, 20, 31, 0, time.UTC),
+	}
+	expected := []*playback.RewindMoment{
+		playback.NewRewindMoment(times[0], fakeMetadata[3], false, false),
+		playback.NewRewindMoment(times[1], fakeMetadata[0], false, false),
+		playback.NewRewindMoment(times[2], fakeMetadata[0], false, false),
+	}
+
+	moments, err := actions.LocateMoments(pb, times, ctx)
+	require.NoError(t, err)
+	if diff := cmp.Diff(expected, moments); diff != "" {
+		t.Fatalf("Mismatch (- expected, + actual):\n%s", diff)
+	}
+}
+
+func TestLocateMoments_SharedBounds(t *testing.T) {
+	t.Parallel()
+
+	// A timeline with uneven gaps, so that locating takes more than a jump
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(3000, 2*time.Second)
+	var shift time.Duration
+	for sq := range len(fakeMetadata) {
+		if sq%7 == 0 {
+			shift += time.Duration(sq*7919%13) * time.Second
+		}
+		m := fakeMetadata[sq]
+		m.IngestionWalltime = m.IngestionWalltime.Add(shift)
+		fakeMetadata[sq] = m
+	}
+
+	ts := httptest.NewServer(
+		http.HandlerFunc(testutil.MakeSegmentMetadataHandler(t, fakeMetadata)),
+	)
+	defer ts.Close()
+
+	newPlayback := func() *playback.Playback {
+		pb, err := playback.NewPlayback(
+			context.Background(),
+			testutil.TestVideoID,
+			&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+			testutil.NewClient(ts.URL),
+			playback.WithMetadataCache(cache.NewMemoryCache(0)),
+		)
+		require.NoError(t, err)
+		return pb
+	}
+
+	head := fakeMetadata[len(fakeMetadata)-1]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	times := make([]time.Time, 60)
+	for i := range times {
+		offset := time.Duration(i)*95*time.Second + 500*time.Millisecond
+		times[i] = fakeMetadata[1000].IngestionWalltime.Add(offset)
+	}
+
+	pb := newPlayback()
+	moments, err := actions.LocateMoments(pb, times, ctx)
+	require.NoError(t, err)
+	shared := pb.LocateStats().MetadataRequests
+
+	// Each target located on its own, starting from the head
+	var separate int64
+	for i, target := range times {
+		pb := newPlayback()
+		moment, err := actions.LocateMoment(pb, target, ctx)
+		require.NoError(t, err)
+		require.Equal(t, moment.Metadata, moments[i].Metadata)
+		separate += pb.LocateStats().MetadataRequests
+	}
+
+	assert.Less(t, shared, separate/2, "shared bounds should save requests")
+}
+
+func TestLocateMoments_AfterHead(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(3, 2*time.Second)
+	pb := newFakePlayback(fakeMetadata)
+	head := fakeMetadata[len(fakeMetadata)-1]
+	ctx := &actions.LocateContext{Head: head, Reference: head}
+
+	_, err := actions.LocateMoments(
+		pb,
+		[]time.Time{time.Date(2026, 1, 2, 23, 59, 59, 0, time.UTC)},
+		ctx,
+	)
+	require.Error(t, err)
+}
+
 func TestLocateInterval(t *testing.T) {
 	t.Parallel()
 
@@ -326,6 +656,13 @@ func TestLocateInterval(t *testing.T) {
 			expectedInterval: expectedInterval,
 			expectedContext:  expectedContext,
 		},
+		{
+			name:             "earliest and now",
+			start:            input.EarliestKeyword,
+			end:              input.NowKeyword,
+			expectedInterval: expectedInterval,
+			expectedContext:  expectedContext,
+		},
 		{
 			name:             "duration and now",
 			start:            4 * time.Second,
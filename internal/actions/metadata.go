@@ -0,0 +1,135 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/exec"
+)
+
+// MediaMetadata is metadata written to output media files.
+type MediaMetadata struct {
+	// Tags are global tags of the container.
+	Tags     map[string]string
+	Chapters []Chapter
+	// Duration is the duration of the media, which ends the last chapter.
+	Duration time.Duration
+}
+
+// IsEmpty reports whether there is no metadata to write.
+func (m *MediaMetadata) IsEmpty() bool {
+	return m == nil || (len(m.Tags) == 0 && len(m.Chapters) == 0)
+}
+
+// FormatFFMetadata formats metadata as an FFmpeg metadata file.
+func FormatFFMetadata(metadata *MediaMetadata) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+
+	for _, key := range slices.Sorted(maps.Keys(metadata.Tags)) {
+		fmt.Fprintf(&b, "%s=%s\n", escapeFFMetadata(key), escapeFFMetadata(metadata.Tags[key]))
+	}
+
+	for i, chapter := range metadata.Chapters {
+		end := chapterEnd(metadata.Chapters, i, metadata.Duration)
+		b.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+		fmt.Fprintf(&b, "START=%d\n", chapter.Offset.Milliseconds())
+		fmt.Fprintf(&b, "END=%d\n", end.Milliseconds())
+		b.WriteString("title=" + escapeFFMetadata(chapter.Title) + "\n")
+	}
+
+	return b.String()
+}
+
+// metadataMuxerArgs returns muxer arguments to write all tags to the output:
+// MP4 files keep only known tags by default.
+func metadataMuxerArgs(outputPath string) []string {
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".mp4", ".m4a", ".mov":
+		return []string{"-movflags", "+use_metadata_tags"}
+	}
+	return nil
+}
+
+// tagArgs returns arguments setting global tags, sorted by key.
+func tagArgs(tags map[string]string) []string {
+	args := make([]string, 0, 2*len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		args = append(args, "-metadata", key+"="+tags[key])
+	}
+	return args
+}
+
+// escapeFFMetadata escapes special characters of FFmpeg metadata values.
+func escapeFFMetadata(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"=", `\=`,
+		";", `\;`,
+		"#", `\#`,
+		"\n", "\\\n",
+	).Replace(s)
+}
+
+// writeFFMetadataFile writes metadata to a temporary FFmpeg metadata file and
+// returns its path. The caller removes the file.
+func writeFFMetadataFile(metadata *MediaMetadata) (string, error) {
+	f, err := os.CreateTemp("", "ypb-*.ffmetadata")
+	if err != nil {
+		return "", fmt.Errorf("creating metadata file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(FormatFFMetadata(metadata)); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing metadata file: %w", err)
+	}
+
+	return f.Name(), f.Close()
+}
+
+// WriteMetadata writes metadata to an existing media file, remuxing it without
+// re-encoding. Tags of the file are kept, unless overridden by the metadata
+// ones, and its chapters are replaced only if the metadata has any.
+func WriteMetadata(path string, metadata *MediaMetadata, runner exec.Runner) error {
+	if metadata.IsEmpty() {
+		return nil
+	}
+
+	tmpPath := filepath.Join(
+		filepath.Dir(path),
+		".tmp."+filepath.Base(path),
+	)
+	args := []string{"-hide_banner", "-y", "-i", path}
+	if len(metadata.Chapters) > 0 {
+		metadataPath, err := writeFFMetadataFile(metadata)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(metadataPath)
+		args = append(args, "-i", metadataPath, "-map_chapters", "1")
+	}
+	args = append(args, "-map", "0", "-map_metadata", "0")
+	args = append(args, tagArgs(metadata.Tags)...)
+	args = append(args, "-c", "copy")
+	args = append(args, metadataMuxerArgs(path)...)
+	args = append(args, tmpPath)
+
+	result, err := runner.RunWith(context.Background(), []exec.Option{exec.WithQuiet()}, args...)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("writing metadata: %w (stderr: %s)", err, result.Stderr)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replacing output file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package actions_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/exec"
+)
+
+// recordingRunner records arguments of commands and creates their output,
+// the last argument.
+type recordingRunner struct {
+	args []string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, args ...string) error {
+	_, err := r.RunWith(ctx, nil, args...)
+	return err
+}
+
+func (r *recordingRunner) RunWith(
+	_ context.Context,
+	_ []exec.Option,
+	args ...string,
+) (*exec.RunResult, error) {
+	r.args = args
+	return &exec.RunResult{}, os.WriteFile(args[len(args)-1], nil, 0o600)
+}
+
+func TestFormatFFMetadata(t *testing.T) {
+	t.Parallel()
+
+	out := actions.FormatFFMetadata(&actions.MediaMetadata{
+		Tags: map[string]string{"title": "Title", "artist": "Channel #1"},
+		Chapters: []actions.Chapter{
+			{Offset: 0, Title: "Intro"},
+			{Offset: 1500 * time.Millisecond, Title: "Q&A; a=b"},
+		},
+		Duration: 6 * time.Second,
+	})
+
+	expected := `;FFMETADATA1
+artist=Channel \#1
+title=Title
+
+[CHAPTER]
+TIMEBASE=1/1000
+START=0
+END=1500
+title=Intro
+
+[CHAPTER]
+TIMEBASE=1/1000
+START=1500
+END=6000
+title=Q&A\; a\=b
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestWriteMetadata(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "output.mp4")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	runner := &recordingRunner{}
+	err := actions.WriteMetadata(path, &actions.MediaMetadata{
+		Tags: map[string]string{"title": "Title", "artist": "Channel"},
+	}, runner)
+	require.NoError(t, err)
+
+	// Tags of the input are kept, and its chapters are not replaced
+	assert.Equal(t, []string{
+		"-hide_banner", "-y",
+		"-i", path,
+		"-map", "0",
+		"-map_metadata", "0",
+		"-metadata", "artist=Channel",
+		"-metadata", "title=Title",
+		"-c", "copy",
+		"-movflags", "+use_metadata_tags",
+		filepath.Join(filepath.Dir(path), ".tmp.output.mp4"),
+	}, runner.args)
+}
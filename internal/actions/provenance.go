@@ -0,0 +1,145 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/urlutil"
+	"github.com/xymaxim/ypb/internal/version"
+)
+
+// provenanceTagPrefix prefixes custom container tags of provenance.
+const provenanceTagPrefix = "ypb_"
+
+// Provenance describes the origin of an output file, so it stays traceable
+// regardless of its name.
+type Provenance struct {
+	VideoID        string    `json:"videoId"`
+	Title          string    `json:"title"`
+	ChannelID      string    `json:"channelId"`
+	Channel        string    `json:"channel"`
+	RequestedStart time.Time `json:"requestedStart"`
+	RequestedEnd   time.Time `json:"requestedEnd"`
+	ActualStart    time.Time `json:"actualStart"`
+	ActualEnd      time.Time `json:"actualEnd"`
+	StartSq        int       `json:"startSq"`
+	EndSq          int       `json:"endSq"`
+	Itags          []string  `json:"itags"`
+	Version        string    `json:"ypbVersion"`
+}
+
+// NewProvenance creates a provenance of the streams with the itags, without
+// times of an interval or moment.
+func NewProvenance(videoInfo info.VideoInformation, itags []string) *Provenance {
+	return &Provenance{
+		VideoID:   videoInfo.ID,
+		Title:     videoInfo.Title,
+		ChannelID: videoInfo.ChannelID,
+		Channel:   videoInfo.ChannelTitle,
+		Itags:     itags,
+		Version:   version.GetShort(),
+	}
+}
+
+// ForInterval returns a copy of the provenance with times of the located
+// interval.
+func (p Provenance) ForInterval(ctx *LocateOutputContext) *Provenance {
+	p.RequestedStart = ctx.InputStartTime
+	p.RequestedEnd = ctx.InputEndTime
+	p.ActualStart = ctx.ActualStartTime
+	p.ActualEnd = ctx.ActualEndTime
+	p.StartSq = ctx.StartSequenceNumber
+	p.EndSq = ctx.EndSequenceNumber
+	return &p
+}
+
+// ForMoment returns a copy of the provenance with times of a captured moment.
+// A frame is captured at the target time, so it is both requested and actual.
+func (p Provenance) ForMoment(moment *playback.RewindMoment) *Provenance {
+	p.RequestedStart, p.RequestedEnd = moment.TargetTime, moment.TargetTime
+	p.ActualStart, p.ActualEnd = moment.TargetTime, moment.TargetTime
+	p.StartSq = moment.Metadata.SequenceNumber
+	p.EndSq = moment.Metadata.SequenceNumber
+	return &p
+}
+
+// Tags returns container tags of the provenance: common ones understood by
+// players, and custom ones prefixed with 'ypb_'.
+func (p *Provenance) Tags() map[string]string {
+	formatTime := func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+
+	tags := map[string]string{
+		"title":   p.Title,
+		"artist":  p.Channel,
+		"comment": urlutil.BuildVideoLiveURL(p.VideoID),
+		"date":    formatTime(p.ActualStart),
+	}
+	custom := map[string]string{
+		"video_id":        p.VideoID,
+		"channel_id":      p.ChannelID,
+		"requested_start": formatTime(p.RequestedStart),
+		"requested_end":   formatTime(p.RequestedEnd),
+		"actual_start":    formatTime(p.ActualStart),
+		"actual_end":      formatTime(p.ActualEnd),
+		"start_sq":        strconv.Itoa(p.StartSq),
+		"end_sq":          strconv.Itoa(p.EndSq),
+		"itags":           strings.Join(p.Itags, ","),
+		"version":         p.Version,
+	}
+	for key, value := range custom {
+		tags[provenanceTagPrefix+key] = value
+	}
+
+	return tags
+}
+
+// WriteSidecar writes the provenance as JSON to the path.
+func (p *Provenance) WriteSidecar(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding provenance: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil { // #nosec G306
+		return fmt.Errorf("writing sidecar file: %w", err)
+	}
+	return nil
+}
+
+// FrameMetadata configures provenance written for captured frames.
+type FrameMetadata struct {
+	// Provenance is completed with the moment of each frame.
+	Provenance *Provenance
+	// Embed embeds the provenance into PNG and JPEG frames.
+	Embed bool
+	// SidecarPath, if not nil, returns the path of a sidecar file for a frame.
+	SidecarPath func(framePath string) string
+}
+
+// write writes metadata of the frame captured at the moment.
+func (m *FrameMetadata) write(framePath string, moment *playback.RewindMoment) error {
+	if m == nil || m.Provenance == nil {
+		return nil
+	}
+
+	provenance := m.Provenance.ForMoment(moment)
+	if m.Embed {
+		if err := WriteImageMetadata(framePath, provenance); err != nil {
+			return err
+		}
+	}
+	if m.SidecarPath != nil {
+		if err := provenance.WriteSidecar(m.SidecarPath(framePath)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package actions_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func newTestProvenance() *actions.Provenance {
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(3, 2*time.Second)
+	moment := playback.NewRewindMoment(
+		time.Date(2026, 1, 2, 10, 20, 33, 0, time.UTC),
+		fakeMetadata[1],
+		false,
+		false,
+	)
+	provenance := &actions.Provenance{
+		VideoID: "abcdefgh123",
+		Title:   "Test title",
+		Channel: "Test channel",
+		Itags:   []string{"137", "140"},
+		Version: "v1.0.0",
+	}
+	return provenance.ForMoment(moment)
+}
+
+func TestProvenance_Tags(t *testing.T) {
+	t.Parallel()
+
+	tags := newTestProvenance().Tags()
+	assert.Equal(t, "Test title", tags["title"])
+	assert.Equal(t, "Test channel", tags["artist"])
+	assert.Equal(t, "abcdefgh123", tags["ypb_video_id"])
+	assert.Equal(t, "2026-01-02T10:20:33Z", tags["ypb_actual_start"])
+	assert.Equal(t, "1", tags["ypb_start_sq"])
+	assert.Equal(t, "137,140", tags["ypb_itags"])
+	assert.Equal(t, "v1.0.0", tags["ypb_version"])
+}
+
+func TestWriteImageMetadata(t *testing.T) {
+	t.Parallel()
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	testCases := []struct {
+		name   string
+		encode func(*bytes.Buffer) error
+		marker string
+		decode func(*bytes.Reader) (image.Image, error)
+	}{
+		{
+			name:   "frame.png",
+			encode: func(b *bytes.Buffer) error { return png.Encode(b, img) },
+			marker: "tEXtTitle\x00Test title",
+			decode: func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) },
+		},
+		{
+			name:   "frame.jpg",
+			encode: func(b *bytes.Buffer) error { return jpeg.Encode(b, img, nil) },
+			marker: "Exif\x00\x00MM",
+			decode: func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) },
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, tc.encode(&buf))
+			path := filepath.Join(t.TempDir(), tc.name)
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+			require.NoError(t, actions.WriteImageMetadata(path, newTestProvenance()))
+
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Contains(t, string(b), tc.marker)
+			assert.Contains(t, string(b), `"videoId":"abcdefgh123"`)
+
+			// The image is still valid
+			_, err = tc.decode(bytes.NewReader(b))
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWriteImageMetadata_NonASCII(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil))
+	path := filepath.Join(t.TempDir(), "frame.jpg")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	provenance := newTestProvenance()
+	provenance.Title = "Тест 🐧"
+	provenance.Channel = "テスト"
+	require.NoError(t, actions.WriteImageMetadata(path, provenance))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), provenance.Channel, "EXIF strings should be ASCII")
+
+	// The description is JSON decoding to the original text
+	start := bytes.Index(b, []byte(`{"videoId"`))
+	require.NotEqual(t, -1, start)
+	end := bytes.IndexByte(b[start:], 0)
+	var decoded actions.Provenance
+	require.NoError(t, json.Unmarshal(b[start:start+end], &decoded))
+	assert.Equal(t, provenance.Title, decoded.Title)
+	assert.Equal(t, provenance.Channel, decoded.Channel)
+}
@@ -0,0 +1,272 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+// RecordChunk is a part of a recording, written to separate track files.
+type RecordChunk struct {
+	Index int
+	First segment.Metadata
+	Count int
+	// Output is the path the chunk is saved to, if ChunkOutput is given.
+	Output string
+	Tracks []Track
+}
+
+// Size returns the total size of track files of a chunk.
+func (c *RecordChunk) Size() int64 {
+	var size int64
+	for _, track := range c.Tracks {
+		size += track.Size
+	}
+	return size
+}
+
+// Duration returns the media duration of a chunk.
+func (c *RecordChunk) Duration() time.Duration {
+	return time.Duration(c.Count) * c.First.Duration
+}
+
+type RecordOptions struct {
+	Itags        []string
+	Start        playback.SequenceNumber
+	PollInterval time.Duration
+	// ShouldStop reports whether to stop before recording a segment.
+	ShouldStop func(m *segment.Metadata) bool
+	// ShouldSplit reports whether to finish a chunk before recording the next
+	// segment to a new one.
+	ShouldSplit func(chunk *RecordChunk) bool
+	// ChunkOutput, if not nil, returns the output path of a new chunk, before
+	// paths of its track files.
+	ChunkOutput func(chunk *RecordChunk) (string, error)
+	// ChunkPath returns the path of a track file of a new chunk.
+	ChunkPath func(chunk *RecordChunk, itag string) string
+	// OnChunk is called after a chunk is finished, e.g., to mux its tracks.
+	OnChunk func(chunk *RecordChunk) error
+	// OnSegment, if not nil, is called after each recorded segment.
+	OnSegment func(chunk *RecordChunk, m *segment.Metadata)
+}
+
+// Failed requests for the head and segment metadata are retried while
+// recording, waiting twice as long each time, starting from the poll
+// interval.
+const (
+	recordRetries      = 5
+	maxRecordRetryWait = time.Minute
+)
+
+// Record records segments starting from the start one, following the head as
+// new segments become available. It stops when the context is done or
+// ShouldStop returns true, finishing the current chunk.
+func Record(ctx context.Context, pb playback.Playbacker, opts RecordOptions) error {
+	r := &recorder{pb: pb, opts: opts}
+
+	err := r.run(ctx)
+	if finishErr := r.finishChunk(); finishErr != nil && err == nil {
+		err = finishErr
+	}
+
+	return err
+}
+
+type recorder struct {
+	pb    playback.Playbacker
+	opts  RecordOptions
+	chunk *RecordChunk
+	index int
+}
+
+func (r *recorder) run(ctx context.Context) error {
+	sq := r.opts.Start
+	for {
+		var head playback.SequenceNumber
+		err := r.retry(ctx, "head segment", func() (err error) {
+			head, err = r.pb.RequestHeadSeqNum()
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("requesting head segment: %w", err)
+		}
+
+		for ; sq <= head; sq++ {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			var m *segment.Metadata
+			err := r.retry(ctx, "segment metadata", func() (err error) {
+				m, err = r.pb.FetchSegmentMetadata(r.pb.ProbeItag(), sq)
+				return err
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("fetching segment metadata, sq=%d: %w", sq, err)
+			}
+			if r.opts.ShouldStop != nil && r.opts.ShouldStop(m) {
+				return nil
+			}
+
+			if err := r.recordSegment(m); err != nil {
+				return err
+			}
+		}
+
+		slog.Debug("waiting for new segments", "head", head)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.opts.PollInterval):
+		}
+	}
+}
+
+// retry calls fn until it succeeds, at most recordRetries more times, with
+// growing waits in between. Unavailable segments are not retried, and retrying
+// stops when the context is done.
+func (r *recorder) retry(ctx context.Context, request string, fn func() error) error {
+	wait := r.opts.PollInterval
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > recordRetries ||
+			errors.Is(err, playback.ErrSegmentUnavailable) {
+			return err
+		}
+
+		slog.Warn(
+			"request failed while recording, retrying",
+			"request", request,
+			"attempt", attempt,
+			"wait", wait,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxRecordRetryWait)
+	}
+}
+
+func (r *recorder) recordSegment(m *segment.Metadata) error {
+	if r.chunk != nil && r.opts.ShouldSplit != nil && r.opts.ShouldSplit(r.chunk) {
+		if err := r.finishChunk(); err != nil {
+			return err
+		}
+	}
+
+	if r.chunk == nil {
+		if err := r.startChunk(m); err != nil {
+			return err
+		}
+	}
+
+	// Tracks are only updated once the segment is appended to all of them, so
+	// that they stay of the same length
+	sizes := make([]int64, len(r.chunk.Tracks))
+	for i := range r.chunk.Tracks {
+		size, err := appendSegment(r.pb, r.chunk.Tracks[i], m.SequenceNumber)
+		if err != nil {
+			return errors.Join(err, r.chunk.truncateTracks())
+		}
+		sizes[i] = size
+	}
+	for i := range r.chunk.Tracks {
+		r.chunk.Tracks[i].Completed++
+		r.chunk.Tracks[i].Size += sizes[i]
+	}
+	r.chunk.Count++
+
+	if r.opts.OnSegment != nil {
+		r.opts.OnSegment(r.chunk, m)
+	}
+
+	return nil
+}
+
+// truncateTracks drops a partially recorded segment from track files of the
+// chunk.
+func (c *RecordChunk) truncateTracks() error {
+	var errs []error
+	for _, track := range c.Tracks {
+		if err := os.Truncate(track.Path, track.Size); err != nil {
+			errs = append(errs, fmt.Errorf("truncating track file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *recorder) startChunk(first *segment.Metadata) error {
+	chunk := &RecordChunk{Index: r.index, First: *first}
+	if r.opts.ChunkOutput != nil {
+		output, err := r.opts.ChunkOutput(chunk)
+		if err != nil {
+			return fmt.Errorf("naming chunk %d: %w", chunk.Index, err)
+		}
+		chunk.Output = output
+	}
+	for _, itag := range r.opts.Itags {
+		track := Track{Itag: itag, Path: r.opts.ChunkPath(chunk, itag)}
+		// Start from an empty file, even if one is left from a previous run
+		f, err := os.Create(track.Path)
+		if err != nil {
+			return fmt.Errorf("creating track file: %w", err)
+		}
+		f.Close()
+		chunk.Tracks = append(chunk.Tracks, track)
+	}
+	r.chunk = chunk
+	return nil
+}
+
+func (r *recorder) finishChunk() error {
+	if r.chunk == nil {
+		return nil
+	}
+
+	chunk := r.chunk
+	r.chunk = nil
+	r.index++
+
+	if err := r.opts.OnChunk(chunk); err != nil {
+		return fmt.Errorf("finishing chunk %d: %w", chunk.Index, err)
+	}
+
+	return nil
+}
+
+// appendSegment appends a segment to a track file.
+func appendSegment(pb playback.Playbacker, track Track, sq playback.SequenceNumber) (int64, error) {
+	var buf bytes.Buffer
+	if err := pb.StreamSegment(track.Itag, sq, &buf); err != nil {
+		return 0, fmt.Errorf("downloading segment, itag=%s, sq=%d: %w", track.Itag, sq, err)
+	}
+
+	f, err := os.OpenFile(track.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("opening track file: %w", err)
+	}
+	defer f.Close()
+
+	size, err := buf.WriteTo(f)
+	if err != nil {
+		return 0, fmt.Errorf("writing segment, itag=%s, sq=%d: %w", track.Itag, sq, err)
+	}
+
+	return size, f.Close()
+}
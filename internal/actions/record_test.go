@@ -0,0 +1,231 @@
+package actions_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+// liveSegmentPlayback advances the head by two segments with each request.
+type liveSegmentPlayback struct {
+	*segmentPlayback
+	head int
+}
+
+func (pb *liveSegmentPlayback) RequestHeadSeqNum() (int, error) {
+	pb.head += 2
+	return pb.head, nil
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &liveSegmentPlayback{
+		segmentPlayback: &segmentPlayback{newFakePlayback(fakeMetadata)},
+		head:            1,
+	}
+
+	dir := t.TempDir()
+	chunks := [][]string{}
+	err := actions.Record(context.Background(), pb, actions.RecordOptions{
+		Itags:        []string{"137"},
+		Start:        2,
+		PollInterval: time.Millisecond,
+		ShouldStop: func(m *segment.Metadata) bool {
+			return m.SequenceNumber > 9
+		},
+		ShouldSplit: func(chunk *actions.RecordChunk) bool {
+			return chunk.Duration() >= 6*time.Second
+		},
+		ChunkPath: func(chunk *actions.RecordChunk, itag string) string {
+			return filepath.Join(dir, strconv.Itoa(chunk.Index)+"."+itag)
+		},
+		OnChunk: func(chunk *actions.RecordChunk) error {
+			b, err := os.ReadFile(chunk.Tracks[0].Path)
+			require.NoError(t, err)
+			chunks = append(chunks, []string{
+				strconv.Itoa(chunk.First.SequenceNumber),
+				string(b),
+			})
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"2", "[137:2][137:3][137:4]"},
+		{"5", "[137:5][137:6][137:7]"},
+		{"8", "[137:8][137:9]"},
+	}, chunks)
+}
+
+func TestRecord_Canceled(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &liveSegmentPlayback{
+		segmentPlayback: &segmentPlayback{newFakePlayback(fakeMetadata)},
+		head:            1,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dir := t.TempDir()
+	finished := 0
+	err := actions.Record(ctx, pb, actions.RecordOptions{
+		Itags:        []string{"137"},
+		Start:        0,
+		PollInterval: time.Hour,
+		ChunkPath: func(_ *actions.RecordChunk, itag string) string {
+			return filepath.Join(dir, itag)
+		},
+		OnSegment: func(_ *actions.RecordChunk, m *segment.Metadata) {
+			if m.SequenceNumber == 2 {
+				cancel()
+			}
+		},
+		OnChunk: func(chunk *actions.RecordChunk) error {
+			finished++
+			assert.Equal(t, 3, chunk.Count)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, finished)
+}
+
+// flakyRecordPlayback fails requests for the head and segment metadata a few
+// times, and streaming a segment of an itag.
+type flakyRecordPlayback struct {
+	*liveSegmentPlayback
+	headFailures     int
+	metadataFailures map[playback.SequenceNumber]int
+	failingItag      string
+	failingSq        playback.SequenceNumber
+}
+
+func (pb *flakyRecordPlayback) RequestHeadSeqNum() (int, error) {
+	if pb.headFailures > 0 {
+		pb.headFailures--
+		return 0, errors.New("got unexpected status: 503 Service Unavailable")
+	}
+	return pb.liveSegmentPlayback.RequestHeadSeqNum()
+}
+
+func (pb *flakyRecordPlayback) FetchSegmentMetadata(
+	itag string,
+	sq playback.SequenceNumber,
+	options ...playback.FetchOption,
+) (*segment.Metadata, error) {
+	if pb.metadataFailures[sq] > 0 {
+		pb.metadataFailures[sq]--
+		return nil, errors.New("got unexpected status: 503 Service Unavailable")
+	}
+	return pb.liveSegmentPlayback.FetchSegmentMetadata(itag, sq, options...)
+}
+
+func (pb *flakyRecordPlayback) StreamSegment(
+	itag string,
+	sq playback.SequenceNumber,
+	w io.Writer,
+) error {
+	if itag == pb.failingItag && sq == pb.failingSq {
+		if _, err := w.Write([]byte("[partial")); err != nil {
+			return err
+		}
+		return errors.New("got unexpected status: 503 Service Unavailable")
+	}
+	return pb.liveSegmentPlayback.StreamSegment(itag, sq, w)
+}
+
+func TestRecord_Retries(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &flakyRecordPlayback{
+		liveSegmentPlayback: &liveSegmentPlayback{
+			segmentPlayback: &segmentPlayback{newFakePlayback(fakeMetadata)},
+			head:            1,
+		},
+		headFailures:     2,
+		metadataFailures: map[playback.SequenceNumber]int{3: 2},
+	}
+
+	dir := t.TempDir()
+	var recorded string
+	err := actions.Record(context.Background(), pb, actions.RecordOptions{
+		Itags:        []string{"137"},
+		Start:        2,
+		PollInterval: time.Millisecond,
+		ShouldStop: func(m *segment.Metadata) bool {
+			return m.SequenceNumber > 4
+		},
+		ChunkPath: func(_ *actions.RecordChunk, itag string) string {
+			return filepath.Join(dir, itag)
+		},
+		OnChunk: func(chunk *actions.RecordChunk) error {
+			b, err := os.ReadFile(chunk.Tracks[0].Path)
+			require.NoError(t, err)
+			recorded = string(b)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "[137:2][137:3][137:4]", recorded)
+}
+
+func TestRecord_FailedSegmentTruncated(t *testing.T) {
+	t.Parallel()
+
+	fakeMetadata := testutil.GenerateFakeSegmentMetadata(20, 2*time.Second)
+	pb := &flakyRecordPlayback{
+		liveSegmentPlayback: &liveSegmentPlayback{
+			segmentPlayback: &segmentPlayback{newFakePlayback(fakeMetadata)},
+			head:            1,
+		},
+		failingItag: "140",
+		failingSq:   4,
+	}
+
+	dir := t.TempDir()
+	recorded := map[string]string{}
+	err := actions.Record(context.Background(), pb, actions.RecordOptions{
+		Itags:        []string{"137", "140"},
+		Start:        2,
+		PollInterval: time.Millisecond,
+		ChunkPath: func(_ *actions.RecordChunk, itag string) string {
+			return filepath.Join(dir, itag)
+		},
+		OnChunk: func(chunk *actions.RecordChunk) error {
+			assert.Equal(t, 2, chunk.Count)
+			for _, track := range chunk.Tracks {
+				b, err := os.ReadFile(track.Path)
+				require.NoError(t, err)
+				recorded[track.Itag] = string(b)
+				assert.Equal(t, 2, track.Completed)
+			}
+			return nil
+		},
+	})
+	require.ErrorContains(t, err, "itag=140, sq=4")
+
+	// The segment appended to the first track is dropped along with the
+	// failed one
+	assert.Equal(t, map[string]string{
+		"137": "[137:2][137:3]",
+		"140": "[140:2][140:3]",
+	}, recorded)
+}
@@ -2,20 +2,36 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/xymaxim/ypb/internal/exec"
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/cache"
 	"github.com/xymaxim/ypb/internal/playback/fetchers"
+	"github.com/xymaxim/ypb/internal/playback/info"
 )
 
 const (
-	InfoPath    = "/info"
-	MPDPath     = "/mpd/{interval}"
-	SegmentPath = "/segments/itag/{itag}/sq/{sq}"
+	// StreamPathPrefix namespaces the routes of a stream in multi-stream mode.
+	StreamPathPrefix = "/{videoID}"
+
+	InfoPath     = "/info"
+	MPDPath      = "/mpd/{interval}"
+	GapsPath     = "/gaps/{interval}"
+	HLSPath      = "/hls/{interval}"
+	HLSMediaPath = "/hls/{interval}/itag/{itag}"
+	SegmentPath  = "/segments/itag/{itag}/sq/{sq}"
+	InitPath     = "/segments/itag/{itag}/sq/{sq}/init"
+	TimePath     = "/time"
+
+	StreamsPath       = "/api/streams"
+	StreamPath        = "/api/streams/{videoID}"
+	StreamRefreshPath = "/api/streams/{videoID}/refresh"
 )
 
 const (
@@ -24,6 +40,9 @@ const (
 	YtdlpBinaryPath   = "yt-dlp"
 )
 
+// App holds resources shared by handlers. Fields are set up by Configure and
+// Initialize before serving and only read afterwards, so handlers may run
+// concurrently; playbacks are safe for concurrent use on their own.
 type App struct {
 	Playback      playback.Playbacker
 	Server        *http.Server
@@ -31,11 +50,32 @@ type App struct {
 	FFmpegRunner  exec.Runner
 	FFprobeRunner exec.Runner
 	YtdlpRunner   exec.Runner
+
+	metadataCache cache.Cache
 }
 
 type Config struct {
-	Port    int
-	OnPrint func([]byte)
+	Port     int
+	CacheDir string
+	// Prefetch is the number of segments to prefetch after each streamed one.
+	Prefetch int
+	// Fetcher is the name of a registered fetcher, yt-dlp by default.
+	Fetcher string
+	// FetcherSource is passed to the fetcher, see fetchers.Options.
+	FetcherSource []string
+	// StreamClock makes time endpoints of streams follow the stream clock.
+	StreamClock bool
+	// Location is the time zone to read times without an offset in requests
+	// in, the local one if nil.
+	Location *time.Location
+	// StreamLocation makes requests of a stream read such times in the
+	// stream's time zone instead, if known.
+	StreamLocation bool
+	// APIToken, if set, is required by the stream management API as a bearer
+	// token. Otherwise, the API only accepts requests from the loopback
+	// interface.
+	APIToken string
+	OnPrint  func([]byte)
 }
 
 func NewApp() *App {
@@ -47,38 +87,134 @@ func NewApp() *App {
 	}
 }
 
+// Initialize configures the app and starts the playback of a single stream.
 func (a *App) Initialize(ctx context.Context, videoID string, cfg *Config) error {
+	if err := a.Configure(cfg); err != nil {
+		return err
+	}
+
+	pb, err := a.NewPlayback(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	a.Playback = pb
+
+	return nil
+}
+
+// Configure sets up the resources shared by all playbacks of the app, without
+// starting any playback.
+func (a *App) Configure(cfg *Config) error {
 	a.Config = cfg
 
+	metadataCache, err := newMetadataCache(cfg.CacheDir)
+	if err != nil {
+		return fmt.Errorf("creating metadata cache: %w", err)
+	}
+	a.metadataCache = metadataCache
+
+	a.Server = &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		ReadHeaderTimeout: 20 * time.Second,
+	}
+
+	return nil
+}
+
+// NewPlayback starts a playback of a stream with its own fetcher. The app
+// should be configured first.
+func (a *App) NewPlayback(ctx context.Context, videoID string) (playback.Playbacker, error) {
+	name := a.Config.Fetcher
+	if name == "" {
+		name = fetchers.YtdlpName
+	}
+	fetcher, err := fetchers.New(name, fetchers.Options{
+		VideoID: videoID,
+		Source:  a.Config.FetcherSource,
+		Runner:  a.YtdlpRunner,
+		OnPrint: a.Config.OnPrint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	pb, err := playback.NewPlayback(
 		ctx,
 		videoID,
-		&fetchers.YtdlpFetcher{
-			VideoID: videoID,
-			Runner:  a.YtdlpRunner,
-			OnPrint: cfg.OnPrint,
-		},
+		fetcher,
 		nil,
+		playback.WithMetadataCache(a.metadataCache),
+		playback.WithPrefetch(a.Config.Prefetch),
 	)
 	if err != nil {
-		return fmt.Errorf("starting playback: %w", err)
+		return nil, fmt.Errorf("starting playback: %w", err)
 	}
-	a.Playback = pb
 
-	a.Server = &http.Server{
-		Addr:              ":" + strconv.Itoa(cfg.Port),
-		ReadHeaderTimeout: 20 * time.Second,
+	return pb, nil
+}
+
+// StreamLocation returns the time zone to read times without an offset in
+// requests of a stream in.
+func (a *App) StreamLocation(videoInfo info.VideoInformation) *time.Location {
+	if a.Config.StreamLocation && videoInfo.Timezone != "" {
+		loc, err := time.LoadLocation(videoInfo.Timezone)
+		if err == nil {
+			return loc
+		}
+		slog.Warn("loading stream time zone", "timezone", videoInfo.Timezone, "error", err)
 	}
+	return a.Config.Location
+}
 
-	return nil
+// orLocal returns the location, or the local time zone if nil.
+func orLocal(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.Local //nolint:gosmopolitan
+	}
+	return loc
+}
+
+// newMetadataCache creates an in-memory cache, backed by an on-disk one if dir
+// is not empty.
+func newMetadataCache(dir string) (cache.Cache, error) {
+	memory := cache.NewMemoryCache(cache.DefaultMemoryCapacity)
+	if dir == "" {
+		return memory, nil
+	}
+
+	disk, err := cache.NewDiskCache(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.Tiered{memory, disk}, nil
+}
+
+// StatusError is an error to respond with a specific HTTP status code.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
 }
 
 func WithError(fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		err := fn(w, r)
 		if err != nil {
-			msg := fmt.Sprintf("%d %s", http.StatusInternalServerError, err.Error())
-			http.Error(w, msg, http.StatusInternalServerError)
+			code := http.StatusInternalServerError
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) {
+				code = statusErr.Code
+			}
+			msg := fmt.Sprintf("%d %s", code, err.Error())
+			http.Error(w, msg, code)
 		}
 	})
 }
@@ -0,0 +1,130 @@
+package app_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/exec"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+	"github.com/xymaxim/ypb/internal/urlutil"
+)
+
+// fakeFFprobe reports zero presentation time for any probed segment.
+type fakeFFprobe struct{}
+
+func (fakeFFprobe) Run(_ context.Context, _ ...string) error {
+	return nil
+}
+
+func (fakeFFprobe) RunWith(
+	_ context.Context,
+	_ []exec.Option,
+	_ ...string,
+) (*exec.RunResult, error) {
+	return &exec.RunResult{Stdout: []byte("0.000000\n")}, nil
+}
+
+// newUpstream serves the head sequence number on requests to base URLs and
+// segment metadata on requests to segments.
+func newUpstream(t *testing.T, data testutil.MetadataMap, head int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Head-Seqnum", strconv.Itoa(head))
+		sqRaw := urlutil.ExtractParameter(r.URL.EscapedPath(), "sq")
+		if sqRaw == "" {
+			return
+		}
+		sq, err := strconv.Atoi(sqRaw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, ok := data[sq]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(testutil.GenerateSegmentMetadataBytes(t, m.SequenceNumber, m.IngestionWalltime))
+	}))
+}
+
+func TestMultiStreamMux_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	const head = 99
+	upstream := newUpstream(t, testutil.GenerateFakeSegmentMetadata(head+1, 2*time.Second), head)
+	defer upstream.Close()
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(upstream.URL),
+	)
+	require.NoError(t, err)
+
+	app := apppkg.NewApp()
+	app.FFprobeRunner = fakeFFprobe{}
+	require.NoError(t, app.Configure(&apppkg.Config{Port: 8080}))
+
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(_ context.Context, _ string) (playback.Playbacker, error) {
+			return pb, nil
+		},
+	)
+	registry.Add(testutil.TestVideoID)
+	mux := apppkg.NewMultiStreamMux(app, registry)
+
+	paths := []string{
+		"/%s/mpd/10--20",
+		"/%s/mpd/earliest--now",
+		"/%s/mpd/50",
+		"/%s/segments/itag/140/sq/30",
+		"/%s/segments/itag/137/sq/31",
+	}
+
+	done := make(chan struct{})
+	var refreshes sync.WaitGroup
+	refreshes.Go(func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			w := httptest.NewRecorder()
+			path := "/api/streams/" + testutil.TestVideoID + "/refresh"
+			r := httptest.NewRequest(http.MethodPost, path, nil)
+			r.RemoteAddr = "127.0.0.1:1234"
+			r.Header.Set("Content-Type", "application/json")
+			mux.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.NoError(t, pb.RefreshBaseURLs())
+		}
+	})
+
+	var requests sync.WaitGroup
+	for i := range 32 {
+		requests.Go(func() {
+			path := fmt.Sprintf(paths[i%len(paths)], testutil.TestVideoID)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusOK, w.Code, "%s: %s", path, w.Body.String())
+		})
+	}
+	requests.Wait()
+	close(done)
+	refreshes.Wait()
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/input"
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+// MaxGapScanSegments limits the number of segments scanned per gaps request,
+// since every segment of the interval is fetched. It covers 12 hours of
+// 2-second segments.
+const MaxGapScanSegments = 21600
+
+// GapEntry describes a single discontinuity in a stream timeline.
+type GapEntry struct {
+	StartSequenceNumber playback.SequenceNumber `json:"startSequenceNumber"`
+	EndSequenceNumber   playback.SequenceNumber `json:"endSequenceNumber"`
+	StartTime           time.Time               `json:"startTime"`
+	EndTime             time.Time               `json:"endTime"`
+	Duration            float64                 `json:"duration"`
+}
+
+// GapReport lists all discontinuities found within a scanned interval.
+type GapReport struct {
+	StartSequenceNumber playback.SequenceNumber `json:"startSequenceNumber"`
+	EndSequenceNumber   playback.SequenceNumber `json:"endSequenceNumber"`
+	StartTime           time.Time               `json:"startTime"`
+	EndTime             time.Time               `json:"endTime"`
+	Gaps                []GapEntry              `json:"gaps"`
+}
+
+// BuildGapReport scans the segments of an interval for gaps.
+func BuildGapReport(pb playback.Playbacker, interval *playback.RewindInterval) (*GapReport, error) {
+	gaps, err := pb.FindGaps(
+		interval.Start.Metadata.SequenceNumber,
+		interval.End.Metadata.SequenceNumber,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("finding gaps: %w", err)
+	}
+
+	report := &GapReport{
+		StartSequenceNumber: interval.Start.Metadata.SequenceNumber,
+		EndSequenceNumber:   interval.End.Metadata.SequenceNumber,
+		StartTime:           interval.Start.Metadata.Time().UTC(),
+		EndTime:             interval.End.Metadata.EndTime().UTC(),
+		Gaps:                make([]GapEntry, 0, len(gaps)),
+	}
+	for _, g := range gaps {
+		report.Gaps = append(report.Gaps, GapEntry{
+			StartSequenceNumber: g.Before.SequenceNumber,
+			EndSequenceNumber:   g.After.SequenceNumber,
+			StartTime:           g.StartTime().UTC(),
+			EndTime:             g.EndTime().UTC(),
+			Duration:            g.Duration().Seconds(),
+		})
+	}
+
+	return report, nil
+}
+
+type GapsHandler struct {
+	Playback playback.Playbacker
+	// Location is the same as for MPDHandler.
+	Location *time.Location
+}
+
+func (h *GapsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	param, err := url.PathUnescape(r.PathValue("interval"))
+	if err != nil {
+		return fmt.Errorf("unescaping interval parameter: %w", err)
+	}
+
+	start, end, err := input.ParseIntervalIn(param, orLocal(h.Location))
+	if err != nil {
+		return fmt.Errorf("parsing interval parameter %q: %w", param, err)
+	}
+	if err := input.ValidateMoments(start, end); err != nil {
+		return fmt.Errorf("bad input interval: %w", err)
+	}
+
+	locateCtx, err := actions.NewLocateContext(h.Playback, nil, nil)
+	if err != nil {
+		return fmt.Errorf("building locate context: %w", err)
+	}
+
+	interval, _, err := actions.LocateInterval(h.Playback, start, end, locateCtx)
+	if err != nil {
+		return fmt.Errorf("locating interval: %w", err)
+	}
+	count := interval.End.Metadata.SequenceNumber - interval.Start.Metadata.SequenceNumber + 1
+	if count > MaxGapScanSegments {
+		return &StatusError{
+			Code: http.StatusBadRequest,
+			Err: fmt.Errorf(
+				"interval is too long to scan: %d segments, at most %d are allowed",
+				count,
+				MaxGapScanSegments,
+			),
+		}
+	}
+
+	report, err := BuildGapReport(h.Playback, interval)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		return fmt.Errorf("writing json response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func TestGapsHandler_MaxSegments(t *testing.T) {
+	t.Parallel()
+
+	const head = apppkg.MaxGapScanSegments
+	data := testutil.GenerateFakeSegmentMetadata(head+1, 2*time.Second)
+	upstream := newUpstream(t, data, head)
+	t.Cleanup(upstream.Close)
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(upstream.URL),
+	)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(apppkg.GapsPath, apppkg.WithError((&apppkg.GapsHandler{
+		Playback: pb,
+	}).ServeHTTP))
+	do := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := do("/gaps/10--20")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(fmt.Sprintf("/gaps/0--%d", head))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "interval is too long to scan")
+}
+
+func TestGapsHandler_Location(t *testing.T) {
+	t.Parallel()
+
+	const head = 99
+	data := testutil.GenerateFakeSegmentMetadata(head+1, 2*time.Second)
+	upstream := newUpstream(t, data, head)
+	t.Cleanup(upstream.Close)
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(upstream.URL),
+	)
+	require.NoError(t, err)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(apppkg.GapsPath, apppkg.WithError((&apppkg.GapsHandler{
+		Playback: pb,
+		Location: tokyo,
+	}).ServeHTTP))
+
+	// Times without an offset are read in the location
+	w := httptest.NewRecorder()
+	const path = "/gaps/2026-01-02T19:20:40%2F2026-01-02T19:20:50"
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	mux.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report apppkg.GapReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, 5, report.StartSequenceNumber)
+	assert.Equal(t, 10, report.EndSequenceNumber)
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/hls"
+	"github.com/xymaxim/ypb/internal/input"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+	"github.com/xymaxim/ypb/internal/urlutil"
+)
+
+const hlsContentType = "application/vnd.apple.mpegurl"
+
+// maxGapScans limits the number of playlist ranges whose gaps are kept.
+const maxGapScans = 64
+
+// hlsRange is an interval resolved to segments.
+type hlsRange struct {
+	Start segment.Metadata
+	End   playback.SequenceNumber
+	Type  hls.PlaylistType
+}
+
+// intervalParam formats the range as an interval parameter of sequence
+// numbers, so that media playlists refer to the same segments regardless of
+// when they are requested.
+func (r *hlsRange) intervalParam() string {
+	if r.Type == hls.PlaylistEvent {
+		return strconv.Itoa(r.Start.SequenceNumber)
+	}
+	return fmt.Sprintf("%d--%d", r.Start.SequenceNumber, r.End)
+}
+
+type HLSHandler struct {
+	Playback playback.Playbacker
+	// Gaps keeps gaps found in media playlists between requests. It should be
+	// kept along with the playback.
+	Gaps       *GapScans
+	ServerAddr string
+	// PathPrefix is the same as for MPDHandler.
+	PathPrefix string
+	// Location is the same as for MPDHandler.
+	Location *time.Location
+}
+
+// ServeMaster responds with a master playlist of an interval.
+func (h *HLSHandler) ServeMaster(w http.ResponseWriter, r *http.Request) error {
+	rng, err := h.resolveInterval(r)
+	if err != nil {
+		return err
+	}
+
+	playlistsURL := h.baseURL() + "hls/" + url.PathEscape(rng.intervalParam()) + "/itag/"
+	out, err := hls.ComposeMaster(h.Playback.Info(), func(itag string) string {
+		return playlistsURL + itag
+	})
+	if err != nil {
+		return fmt.Errorf("composing master playlist: %w", err)
+	}
+
+	return writePlaylist(w, out)
+}
+
+// ServeMedia responds with a media playlist of an interval for an itag.
+// Playlists of open-ended intervals end with the current head segment. Gaps in
+// the stream timeline are marked as discontinuities.
+func (h *HLSHandler) ServeMedia(w http.ResponseWriter, r *http.Request) error {
+	rng, err := h.resolveInterval(r)
+	if err != nil {
+		return err
+	}
+
+	gaps, err := h.Gaps.Find(h.Playback, rng.Start.SequenceNumber, rng.End)
+	if err != nil {
+		return err
+	}
+	discontinuities := make([]hls.Discontinuity, 0, len(gaps))
+	for _, g := range gaps {
+		discontinuities = append(discontinuities, hls.Discontinuity{
+			SequenceNumber: g.After.SequenceNumber,
+			StartTime:      g.EndTime(),
+		})
+	}
+
+	out, err := hls.ComposeMedia(hls.MediaOptions{
+		BaseURL:         h.baseURL(),
+		Itag:            r.PathValue("itag"),
+		Type:            rng.Type,
+		StartNumber:     rng.Start.SequenceNumber,
+		SegmentCount:    rng.End - rng.Start.SequenceNumber + 1,
+		SegmentDuration: h.Playback.Info().SegmentDuration,
+		StartTime:       rng.Start.Time(),
+		Discontinuities: discontinuities,
+	})
+	if err != nil {
+		return fmt.Errorf("composing media playlist: %w", err)
+	}
+
+	return writePlaylist(w, out)
+}
+
+// GapScans keeps gaps found in ranges of media playlists, keyed by their start
+// segments. Players reload event playlists every target duration, so a range
+// is only scanned from its last scanned segment on each reload.
+type GapScans struct {
+	mu    sync.Mutex
+	scans map[playback.SequenceNumber]*gapScan
+}
+
+type gapScan struct {
+	mu sync.Mutex
+	// last is the last scanned segment.
+	last playback.SequenceNumber
+	gaps []playback.Gap
+}
+
+func NewGapScans() *GapScans {
+	return &GapScans{scans: make(map[playback.SequenceNumber]*gapScan)}
+}
+
+// Find returns the gaps between the start and end segments, inclusive, scanning
+// only the segments after the last scanned one of the range. At most
+// MaxGapScanSegments are scanned per call, as with GapsHandler: gaps past them
+// are left out and found by the next calls.
+func (s *GapScans) Find(
+	pb playback.Playbacker,
+	start, end playback.SequenceNumber,
+) ([]playback.Gap, error) {
+	scan := s.scan(start)
+
+	scan.mu.Lock()
+	defer scan.mu.Unlock()
+
+	if end > scan.last {
+		scanEnd := min(end, scan.last+MaxGapScanSegments-1)
+		if scanEnd < end {
+			slog.Warn(
+				"range is too long to scan at once, leaving out later gaps",
+				"start", start,
+				"scanned", scanEnd,
+				"end", end,
+			)
+		}
+		gaps, err := pb.FindGaps(scan.last, scanEnd)
+		if err != nil {
+			return nil, fmt.Errorf("finding gaps: %w", err)
+		}
+		scan.gaps = append(scan.gaps, gaps...)
+		scan.last = scanEnd
+	}
+
+	n := len(scan.gaps)
+	for n > 0 && scan.gaps[n-1].After.SequenceNumber > end {
+		n--
+	}
+
+	return slices.Clone(scan.gaps[:n]), nil
+}
+
+// scan returns the scan of a range starting from the segment, evicting an
+// arbitrary one if there are too many.
+func (s *GapScans) scan(start playback.SequenceNumber) *gapScan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scan, ok := s.scans[start]; ok {
+		return scan
+	}
+	if len(s.scans) >= maxGapScans {
+		for sq := range s.scans {
+			delete(s.scans, sq)
+			break
+		}
+	}
+	scan := &gapScan{last: start}
+	s.scans[start] = scan
+
+	return scan
+}
+
+func (h *HLSHandler) resolveInterval(r *http.Request) (*hlsRange, error) {
+	param, err := url.PathUnescape(r.PathValue("interval"))
+	if err != nil {
+		return nil, fmt.Errorf("unescaping interval parameter: %w", err)
+	}
+
+	locateCtx, err := actions.NewLocateContext(h.Playback, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building locate context: %w", err)
+	}
+
+	if !strings.Contains(param, "/") && !strings.Contains(param, "--") {
+		parsed, err := input.ParseIntervalPartIn(param, orLocal(h.Location))
+		if err != nil {
+			return nil, fmt.Errorf("parsing interval parameter %q: %w", param, err)
+		}
+		moment, err := actions.LocateMoment(h.Playback, parsed, locateCtx)
+		if err != nil {
+			return nil, fmt.Errorf("locating moment: %w", err)
+		}
+		return &hlsRange{
+			Start: moment.Metadata,
+			End:   locateCtx.Head.SequenceNumber,
+			Type:  hls.PlaylistEvent,
+		}, nil
+	}
+
+	start, end, err := input.ParseIntervalIn(param, orLocal(h.Location))
+	if err != nil {
+		return nil, fmt.Errorf("parsing interval parameter %q: %w", param, err)
+	}
+	if err := input.ValidateMoments(start, end); err != nil {
+		return nil, fmt.Errorf("bad input interval: %w", err)
+	}
+
+	interval, _, err := actions.LocateInterval(h.Playback, start, end, locateCtx)
+	if err != nil {
+		return nil, fmt.Errorf("locating interval: %w", err)
+	}
+
+	return &hlsRange{
+		Start: interval.Start.Metadata,
+		End:   interval.End.Metadata.SequenceNumber,
+		Type:  hls.PlaylistVOD,
+	}, nil
+}
+
+func (h *HLSHandler) baseURL() string {
+	return urlutil.FormatServerAddress(h.ServerAddr) + cmp.Or(h.PathPrefix, "/")
+}
+
+func writePlaylist(w http.ResponseWriter, playlist string) error {
+	w.Header().Set("Content-Type", hlsContentType)
+	if _, err := w.Write([]byte(playlist)); err != nil {
+		return fmt.Errorf("writing playlist: %w", err)
+	}
+	return nil
+}
+
+// InitHandler serves the initialization part of a segment, used as a media
+// initialization section (EXT-X-MAP) in HLS.
+type InitHandler struct {
+	Playback playback.Playbacker
+}
+
+func (h *InitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	sq, err := strconv.Atoi(r.PathValue("sq"))
+	if err != nil {
+		return fmt.Errorf("parsing sq parameter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := h.Playback.StreamSegment(r.PathValue("itag"), sq, &buf); err != nil {
+		return fmt.Errorf("downloading segment, sq=%d: %w", sq, err)
+	}
+
+	init, err := segment.ExtractInit(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("extracting initialization, sq=%d: %w", sq, err)
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	if _, err := w.Write(init); err != nil {
+		return fmt.Errorf("writing initialization: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+// scanningPlayback records ranges scanned for gaps.
+type scanningPlayback struct {
+	playback.Playbacker
+	data    testutil.MetadataMap
+	scanned [][2]playback.SequenceNumber
+}
+
+func (pb *scanningPlayback) FindGaps(start, end playback.SequenceNumber) ([]playback.Gap, error) {
+	pb.scanned = append(pb.scanned, [2]playback.SequenceNumber{start, end})
+	var gaps []playback.Gap
+	for sq := start + 1; sq <= end; sq++ {
+		if sq%10 == 0 {
+			gaps = append(gaps, playback.Gap{Before: pb.data[sq-1], After: pb.data[sq]})
+		}
+	}
+	return gaps, nil
+}
+
+func TestGapScans_Find(t *testing.T) {
+	t.Parallel()
+
+	pb := &scanningPlayback{
+		data: testutil.GenerateFakeSegmentMetadata(apppkg.MaxGapScanSegments+100, 2*time.Second),
+	}
+	scans := apppkg.NewGapScans()
+
+	find := func(start, end playback.SequenceNumber) []playback.SequenceNumber {
+		t.Helper()
+		gaps, err := scans.Find(pb, start, end)
+		require.NoError(t, err)
+		afters := []playback.SequenceNumber{}
+		for _, g := range gaps {
+			afters = append(afters, g.After.SequenceNumber)
+		}
+		return afters
+	}
+
+	assert.Equal(t, []playback.SequenceNumber{10, 20}, find(5, 25))
+	assert.Equal(t, []playback.SequenceNumber{10, 20, 30}, find(5, 32))
+	assert.Equal(t, []playback.SequenceNumber{10}, find(5, 15))
+	assert.Equal(t, [][2]playback.SequenceNumber{{5, 25}, {25, 32}}, pb.scanned)
+
+	// Long ranges are scanned in parts, one per call
+	const longEnd = 50 + apppkg.MaxGapScanSegments
+	pb.scanned = nil
+	gaps := find(50, longEnd)
+	assert.Equal(t, playback.SequenceNumber(longEnd-10), gaps[len(gaps)-1])
+	gaps = find(50, longEnd)
+	assert.Equal(t, playback.SequenceNumber(longEnd), gaps[len(gaps)-1])
+	assert.Equal(
+		t,
+		[][2]playback.SequenceNumber{{50, longEnd - 1}, {longEnd - 1, longEnd}},
+		pb.scanned,
+	)
+}
@@ -17,6 +17,16 @@ type jsonInfo struct {
 	ActualStartTime time.Time `json:"actualStartTime"`
 }
 
+func newJSONInfo(info info.VideoInformation) *jsonInfo {
+	return &jsonInfo{
+		ID:              info.ID,
+		Title:           info.Title,
+		ChannelID:       info.ChannelID,
+		ChannelTitle:    info.ChannelTitle,
+		ActualStartTime: info.ActualStartTime,
+	}
+}
+
 type InfoHandler struct {
 	Info info.VideoInformation
 }
@@ -24,15 +34,7 @@ type InfoHandler struct {
 func (h *InfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
 
-	content := jsonInfo{
-		ID:              h.Info.ID,
-		Title:           h.Info.Title,
-		ChannelID:       h.Info.ChannelID,
-		ChannelTitle:    h.Info.ChannelTitle,
-		ActualStartTime: h.Info.ActualStartTime,
-	}
-
-	err := json.NewEncoder(w).Encode(content)
+	err := json.NewEncoder(w).Encode(newJSONInfo(h.Info))
 	if err != nil {
 		return fmt.Errorf("writing json response: %w", err)
 	}
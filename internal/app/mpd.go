@@ -2,9 +2,11 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/xymaxim/ypb/internal/exec"
 	"github.com/xymaxim/ypb/internal/input"
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/info"
 	"github.com/xymaxim/ypb/internal/urlutil"
 )
 
@@ -37,9 +40,15 @@ type mpdResponse struct {
 }
 
 type MPDHandler struct {
-	Playback      playback.Playbacker
-	ServerAddr    string
+	Playback   playback.Playbacker
+	ServerAddr string
+	// PathPrefix is the path segments are served under, with a trailing
+	// slash (e.g., "/<videoID>/"). Empty for the root.
+	PathPrefix    string
 	FFprobeRunner exec.Runner
+	// Location is the time zone to read times without an offset in, the
+	// local one if nil.
+	Location *time.Location
 }
 
 func (h *MPDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
@@ -48,14 +57,85 @@ func (h *MPDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
 		return fmt.Errorf("unescaping interval parameter: %w", err)
 	}
 
+	window, err := parseWindow(r)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		return err
+	}
+	pb, err := actions.FilterStreams(h.Playback, filter)
+	if err != nil {
+		return &StatusError{Code: http.StatusBadRequest, Err: err}
+	}
+	filtered := *h
+	filtered.Playback = pb
+
 	if !strings.Contains(param, "/") && !strings.Contains(param, "--") {
-		return h.respondDynamicMPD(w, r, param)
+		return filtered.respondDynamicMPD(w, r, param, window)
+	}
+	if window > 0 {
+		return &StatusError{
+			Code: http.StatusBadRequest,
+			Err:  errors.New("window is only supported for open-ended intervals"),
+		}
+	}
+	return filtered.respondStaticMPD(w, r, param)
+}
+
+// parseStreamFilter parses the optional query parameters restricting
+// representations: itags (comma-separated), maxHeight, video, and audio.
+func parseStreamFilter(r *http.Request) (info.StreamFilter, error) {
+	query := r.URL.Query()
+
+	filter := info.StreamFilter{
+		Video: query.Get("video"),
+		Audio: query.Get("audio"),
+	}
+	for itag := range strings.SplitSeq(query.Get("itags"), ",") {
+		if itag = strings.TrimSpace(itag); itag != "" {
+			filter.Itags = append(filter.Itags, itag)
+		}
 	}
-	return h.respondStaticMPD(w, r, param)
+	if raw := query.Get("maxHeight"); raw != "" {
+		maxHeight, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, &StatusError{
+				Code: http.StatusBadRequest,
+				Err:  fmt.Errorf("bad maxHeight parameter: %q", raw),
+			}
+		}
+		filter.MaxHeight = maxHeight
+	}
+
+	if err := filter.Validate(); err != nil {
+		return filter, &StatusError{Code: http.StatusBadRequest, Err: err}
+	}
+
+	return filter, nil
+}
+
+// parseWindow parses the optional time-shift window query parameter, given as
+// a Go duration (e.g., "2h" or "90m").
+func parseWindow(r *http.Request) (time.Duration, error) {
+	raw := r.URL.Query().Get("window")
+	if raw == "" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil || window <= 0 {
+		return 0, &StatusError{
+			Code: http.StatusBadRequest,
+			Err:  fmt.Errorf("bad window parameter: %q", raw),
+		}
+	}
+	return window, nil
 }
 
 func (h *MPDHandler) respondStaticMPD(w http.ResponseWriter, r *http.Request, param string) error {
-	startParsed, endParsed, err := input.ParseInterval(param)
+	startParsed, endParsed, err := input.ParseIntervalIn(param, orLocal(h.Location))
 	if err != nil {
 		return fmt.Errorf("parsing interval parameter %q: %w", param, err)
 	}
@@ -82,7 +162,8 @@ func (h *MPDHandler) respondStaticMPD(w http.ResponseWriter, r *http.Request, pa
 	mpd, err := actions.ComposeStatic(
 		h.Playback,
 		rewindInterval,
-		urlutil.FormatServerAddress(h.ServerAddr),
+		nil,
+		h.baseURL(),
 		h.FFprobeRunner,
 	)
 	if err != nil {
@@ -100,8 +181,13 @@ func (h *MPDHandler) respondStaticMPD(w http.ResponseWriter, r *http.Request, pa
 	})
 }
 
-func (h *MPDHandler) respondDynamicMPD(w http.ResponseWriter, r *http.Request, param string) error {
-	parsed, err := input.ParseIntervalPart(param)
+func (h *MPDHandler) respondDynamicMPD(
+	w http.ResponseWriter,
+	r *http.Request,
+	param string,
+	window time.Duration,
+) error {
+	parsed, err := input.ParseIntervalPartIn(param, orLocal(h.Location))
 	if err != nil {
 		return fmt.Errorf("parsing interval parameter %q: %w", param, err)
 	}
@@ -119,7 +205,9 @@ func (h *MPDHandler) respondDynamicMPD(w http.ResponseWriter, r *http.Request, p
 	out, err := actions.ComposeDynamic(
 		h.Playback,
 		rewindMoment,
-		urlutil.FormatServerAddress(h.ServerAddr),
+		locateCtx.Head,
+		h.baseURL(),
+		window,
 		h.FFprobeRunner,
 	)
 	if err != nil {
@@ -131,6 +219,10 @@ func (h *MPDHandler) respondDynamicMPD(w http.ResponseWriter, r *http.Request, p
 	})
 }
 
+func (h *MPDHandler) baseURL() string {
+	return urlutil.FormatServerAddress(h.ServerAddr) + h.PathPrefix
+}
+
 func (h *MPDHandler) serveMPD(
 	w http.ResponseWriter,
 	r *http.Request,
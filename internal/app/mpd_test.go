@@ -0,0 +1,120 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func newTestMPDMux(t *testing.T) func(path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	const head = 99
+	return newTestMPDMuxWith(t, testutil.GenerateFakeSegmentMetadata(head+1, 2*time.Second), head)
+}
+
+func newTestMPDMuxWith(
+	t *testing.T,
+	data testutil.MetadataMap,
+	head int,
+) func(path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	upstream := newUpstream(t, data, head)
+	t.Cleanup(upstream.Close)
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(upstream.URL),
+	)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(apppkg.MPDPath, apppkg.WithError((&apppkg.MPDHandler{
+		Playback:      pb,
+		FFprobeRunner: fakeFFprobe{},
+		ServerAddr:    ":8080",
+	}).ServeHTTP))
+
+	return func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+}
+
+func TestMPDHandler_Window(t *testing.T) {
+	t.Parallel()
+	do := newTestMPDMux(t)
+
+	w := do("/mpd/50?window=2h")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	assert.Contains(t, body, `availabilityStartTime="2026-01-02T10:22:10.000Z"`)
+	assert.Contains(t, body, `timeShiftBufferDepth="PT2H0M0S"`)
+	assert.Contains(t, body, `minimumUpdatePeriod="PT30S"`)
+	assert.Contains(t, body, `suggestedPresentationDelay="PT6S"`)
+	assert.Contains(
+		t,
+		body,
+		`<UTCTiming schemeIdUri="urn:mpeg:dash:utc:http-iso:2014" value="http://localhost:8080/time">`,
+	)
+
+	w = do("/mpd/50")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "timeShiftBufferDepth")
+
+	assert.Equal(t, http.StatusBadRequest, do("/mpd/50?window=bad").Code)
+	assert.Equal(t, http.StatusBadRequest, do("/mpd/50?window=-1h").Code)
+	assert.Equal(t, http.StatusBadRequest, do("/mpd/10--20?window=2h").Code)
+}
+
+func TestMPDHandler_WindowAfterGap(t *testing.T) {
+	t.Parallel()
+
+	const head = 99
+	data := testutil.GenerateFakeSegmentMetadata(head+1, 2*time.Second)
+	for sq := 60; sq <= head; sq++ {
+		m := data[sq]
+		m.IngestionWalltime = m.IngestionWalltime.Add(time.Minute)
+		data[sq] = m
+	}
+	do := newTestMPDMuxWith(t, data, head)
+
+	// The head is at 10:24:48, and 49 segments before it is 10:23:10
+	w := do("/mpd/50?window=2h")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `availabilityStartTime="2026-01-02T10:23:10.000Z"`)
+}
+
+func TestMPDHandler_Filter(t *testing.T) {
+	t.Parallel()
+	do := newTestMPDMux(t)
+
+	w := do("/mpd/10--20?itags=137,140")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `<Representation id="137"`)
+	assert.Contains(t, w.Body.String(), `<Representation id="140"`)
+	assert.NotContains(t, w.Body.String(), `<Representation id="136"`)
+
+	w = do("/mpd/50?maxHeight=720&audio=none")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `<Representation id="136"`)
+	assert.NotContains(t, w.Body.String(), `<Representation id="137"`)
+	assert.NotContains(t, w.Body.String(), `<Representation id="140"`)
+
+	assert.Equal(t, http.StatusBadRequest, do("/mpd/50?maxHeight=high").Code)
+	assert.Equal(t, http.StatusBadRequest, do("/mpd/50?video=worst").Code)
+	assert.Equal(t, http.StatusBadRequest, do("/mpd/50?itags=999").Code)
+}
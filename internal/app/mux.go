@@ -0,0 +1,135 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+// NewMultiStreamMux creates a mux serving all streams of a registry under
+// their video ID prefixes, along with the stream management API.
+func NewMultiStreamMux(a *App, registry *Registry) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		StreamPathPrefix+InfoPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return (&InfoHandler{Info: pb.Info()}).ServeHTTP
+			},
+		)),
+	)
+	mux.HandleFunc(
+		StreamPathPrefix+MPDPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return (&MPDHandler{
+					Playback:      pb,
+					FFprobeRunner: a.FFprobeRunner,
+					ServerAddr:    a.Server.Addr,
+					PathPrefix:    "/" + pb.Info().ID + "/",
+					Location:      a.StreamLocation(pb.Info()),
+				}).ServeHTTP
+			},
+		)),
+	)
+	mux.HandleFunc(
+		StreamPathPrefix+GapsPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return (&GapsHandler{
+					Playback: pb,
+					Location: a.StreamLocation(pb.Info()),
+				}).ServeHTTP
+			},
+		)),
+	)
+	mux.HandleFunc(
+		StreamPathPrefix+HLSPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return newPrefixedHLSHandler(a, pb, nil).ServeMaster
+			},
+		)),
+	)
+	mux.HandleFunc(
+		StreamPathPrefix+HLSMediaPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return func(w http.ResponseWriter, r *http.Request) error {
+					gaps, err := registry.GapScans(r.PathValue("videoID"))
+					if err != nil {
+						return withNotFound(err)
+					}
+					return newPrefixedHLSHandler(a, pb, gaps).ServeMedia(w, r)
+				}
+			},
+		)),
+	)
+	mux.HandleFunc(
+		StreamPathPrefix+InitPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return (&InitHandler{Playback: pb}).ServeHTTP
+			},
+		)),
+	)
+	mux.HandleFunc(
+		StreamPathPrefix+SegmentPath,
+		WithError(registry.WithPlayback(
+			func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return (&SegmentHandler{Playback: pb}).ServeHTTP
+			},
+		)),
+	)
+
+	localTime := &TimeHandler{}
+	mux.HandleFunc(TimePath, WithError(localTime.ServeHTTP))
+	mux.HandleFunc(
+		StreamPathPrefix+TimePath,
+		WithError(func(w http.ResponseWriter, r *http.Request) error {
+			videoID := r.PathValue("videoID")
+			if !a.Config.StreamClock {
+				if !registry.Has(videoID) {
+					return withNotFound(fmt.Errorf("%w: %s", ErrStreamNotFound, videoID))
+				}
+				return localTime.ServeHTTP(w, r)
+			}
+			h, err := registry.TimeHandler(videoID)
+			if err != nil {
+				return withNotFound(err)
+			}
+			return h.ServeHTTP(w, r)
+		}),
+	)
+
+	streamsHandler := &StreamsHandler{Registry: registry, Token: a.Config.APIToken}
+	mux.HandleFunc(
+		"GET "+StreamsPath,
+		WithError(streamsHandler.Authorize(streamsHandler.List)),
+	)
+	mux.HandleFunc(
+		"POST "+StreamPath,
+		WithError(streamsHandler.Authorize(streamsHandler.Add)),
+	)
+	mux.HandleFunc(
+		"DELETE "+StreamPath,
+		WithError(streamsHandler.Authorize(streamsHandler.Remove)),
+	)
+	mux.HandleFunc(
+		"POST "+StreamRefreshPath,
+		WithError(streamsHandler.Authorize(streamsHandler.Refresh)),
+	)
+
+	return mux
+}
+
+func newPrefixedHLSHandler(a *App, pb playback.Playbacker, gaps *GapScans) *HLSHandler {
+	return &HLSHandler{
+		Playback:   pb,
+		Gaps:       gaps,
+		ServerAddr: a.Server.Addr,
+		PathPrefix: "/" + pb.Info().ID + "/",
+		Location:   a.StreamLocation(pb.Info()),
+	}
+}
@@ -0,0 +1,284 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+// ErrStreamNotFound is returned when a stream is not registered.
+var ErrStreamNotFound = errors.New("stream not found")
+
+// StartPlaybackFunc starts a playback of a stream.
+type StartPlaybackFunc func(ctx context.Context, videoID string) (playback.Playbacker, error)
+
+// Registry holds playbacks of multiple streams keyed by video ID. Streams can
+// be added and removed at any time, and their playbacks are started lazily on
+// first use.
+type Registry struct {
+	ctx     context.Context
+	start   StartPlaybackFunc
+	mu      sync.Mutex
+	streams map[string]*registryEntry
+}
+
+type registryEntry struct {
+	mu     sync.Mutex
+	pb     playback.Playbacker
+	cancel context.CancelFunc
+	// starting is the ongoing start of the playback, if any. The entry is not
+	// locked while starting, since it takes seconds.
+	starting *startAttempt
+	removed  bool
+	// time measures the stream clock offset, kept along with the playback.
+	time *TimeHandler
+	// gaps keeps gaps found in media playlists of the stream.
+	gaps *GapScans
+}
+
+// startAttempt is a start of a playback shared by concurrent callers.
+type startAttempt struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRegistry creates an empty registry. Playbacks are started with contexts
+// derived from ctx, which are canceled when their streams are removed.
+func NewRegistry(ctx context.Context, start StartPlaybackFunc) *Registry {
+	return &Registry{
+		ctx:     ctx,
+		start:   start,
+		streams: make(map[string]*registryEntry),
+	}
+}
+
+// Add registers a stream without starting its playback. It returns false if
+// the stream is already registered.
+func (rg *Registry) Add(videoID string) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if _, ok := rg.streams[videoID]; ok {
+		return false
+	}
+	rg.streams[videoID] = &registryEntry{}
+
+	return true
+}
+
+// Remove unregisters a stream. It returns false if the stream is not
+// registered.
+func (rg *Registry) Remove(videoID string) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	entry, ok := rg.streams[videoID]
+	if !ok {
+		return false
+	}
+	delete(rg.streams, videoID)
+
+	entry.mu.Lock()
+	entry.removed = true
+	if entry.cancel != nil {
+		entry.cancel()
+	}
+	entry.mu.Unlock()
+
+	return true
+}
+
+// Has reports whether a stream is registered.
+func (rg *Registry) Has(videoID string) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	_, ok := rg.streams[videoID]
+	return ok
+}
+
+// IDs returns the sorted video IDs of all registered streams.
+func (rg *Registry) IDs() []string {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	ids := make([]string, 0, len(rg.streams))
+	for id := range rg.streams {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// Started returns the playback of a registered stream if it has already been
+// started, without starting it.
+func (rg *Registry) Started(videoID string) (playback.Playbacker, bool) {
+	rg.mu.Lock()
+	entry, ok := rg.streams[videoID]
+	rg.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	return entry.pb, entry.pb != nil
+}
+
+// Playback returns the playback of a registered stream, starting it if needed.
+// A failed start is retried on the next call.
+func (rg *Registry) Playback(videoID string) (playback.Playbacker, error) {
+	entry, err := rg.startedEntry(videoID)
+	if err != nil {
+		return nil, err
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	return entry.pb, nil
+}
+
+// TimeHandler returns the time handler measuring the clock of a registered
+// stream, starting its playback if needed. The handler is kept until the
+// stream is removed, so the measured offset is reused between requests.
+func (rg *Registry) TimeHandler(videoID string) (*TimeHandler, error) {
+	entry, err := rg.startedEntry(videoID)
+	if err != nil {
+		return nil, err
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.time == nil {
+		entry.time = &TimeHandler{Playback: entry.pb}
+	}
+
+	return entry.time, nil
+}
+
+// GapScans returns the gaps found in media playlists of a registered stream,
+// starting its playback if needed. They are kept until the stream is removed.
+func (rg *Registry) GapScans(videoID string) (*GapScans, error) {
+	entry, err := rg.startedEntry(videoID)
+	if err != nil {
+		return nil, err
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.gaps == nil {
+		entry.gaps = NewGapScans()
+	}
+
+	return entry.gaps, nil
+}
+
+// startedEntry returns the entry of a registered stream with its playback
+// started. Concurrent callers wait for the same start, which is canceled if the
+// stream is removed meanwhile.
+func (rg *Registry) startedEntry(videoID string) (*registryEntry, error) {
+	rg.mu.Lock()
+	entry, ok := rg.streams[videoID]
+	rg.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, videoID)
+	}
+
+	entry.mu.Lock()
+	switch {
+	case entry.removed:
+		entry.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, videoID)
+	case entry.pb != nil:
+		entry.mu.Unlock()
+		return entry, nil
+	case entry.starting != nil:
+		attempt := entry.starting
+		entry.mu.Unlock()
+		<-attempt.done
+		if attempt.err != nil {
+			return nil, attempt.err
+		}
+		return entry, nil
+	}
+	attempt := &startAttempt{done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(rg.ctx)
+	entry.starting, entry.cancel = attempt, cancel
+	entry.mu.Unlock()
+
+	pb, err := rg.start(ctx, videoID)
+
+	entry.mu.Lock()
+	entry.starting = nil
+	switch {
+	case err != nil:
+		attempt.err = fmt.Errorf("starting playback of %s: %w", videoID, err)
+	case entry.removed:
+		attempt.err = fmt.Errorf("%w: %s", ErrStreamNotFound, videoID)
+	default:
+		entry.pb = pb
+	}
+	if attempt.err != nil {
+		cancel()
+		entry.cancel = nil
+	}
+	entry.mu.Unlock()
+	close(attempt.done)
+
+	if attempt.err != nil {
+		return nil, attempt.err
+	}
+	return entry, nil
+}
+
+// WithPlayback wraps a handler built for the playback of the stream requested
+// in the videoID path value.
+func (rg *Registry) WithPlayback(
+	build func(pb playback.Playbacker) func(http.ResponseWriter, *http.Request) error,
+) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		pb, err := rg.Playback(r.PathValue("videoID"))
+		if err != nil {
+			return withNotFound(err)
+		}
+		return build(pb)(w, r)
+	}
+}
+
+// WithDefaultStream routes requests to unprefixed stream endpoints, such as
+// /mpd/{interval}, to the default stream, keeping URLs of serving a single
+// stream without video ID prefixes. Requests to registered streams, the
+// stream management API, and the root time endpoint are passed as is.
+func (rg *Registry) WithDefaultStream(videoID string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if r.URL.Path == TimePath || strings.HasPrefix(r.URL.Path, StreamsPath) ||
+			rg.Has(first) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.URL.Path = "/" + videoID + r.URL.Path
+		if r.URL.RawPath != "" {
+			r.URL.RawPath = "/" + videoID + r.URL.RawPath
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withNotFound responds with 404 Not Found to unregistered streams.
+func withNotFound(err error) error {
+	if errors.Is(err, ErrStreamNotFound) {
+		return &StatusError{Code: http.StatusNotFound, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+func TestRegistry_Playback(t *testing.T) {
+	t.Parallel()
+
+	started := map[string]int{}
+	contexts := map[string]context.Context{}
+	failing := true
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(ctx context.Context, videoID string) (playback.Playbacker, error) {
+			started[videoID]++
+			contexts[videoID] = ctx
+			if videoID == "failing" && failing {
+				return nil, errors.New("unavailable")
+			}
+			return &playback.Playback{}, nil
+		},
+	)
+
+	assert.True(t, registry.Add("a"))
+	assert.True(t, registry.Add("failing"))
+	assert.False(t, registry.Add("a"))
+	assert.Equal(t, []string{"a", "failing"}, registry.IDs())
+	assert.Empty(t, started, "playbacks should be started lazily")
+
+	first, err := registry.Playback("a")
+	require.NoError(t, err)
+	second, err := registry.Playback("a")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, started["a"])
+
+	_, err = registry.Playback("failing")
+	require.Error(t, err)
+	failing = false
+	_, err = registry.Playback("failing")
+	require.NoError(t, err)
+	assert.Equal(t, 2, started["failing"])
+
+	require.NoError(t, contexts["a"].Err())
+	assert.True(t, registry.Remove("a"))
+	assert.ErrorIs(t, contexts["a"].Err(), context.Canceled, "removing should stop playback")
+	assert.False(t, registry.Remove("a"))
+	_, err = registry.Playback("a")
+	require.ErrorIs(t, err, apppkg.ErrStreamNotFound)
+}
+
+func TestRegistry_RemoveWhileStarting(t *testing.T) {
+	t.Parallel()
+
+	entered := make(chan struct{})
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(ctx context.Context, _ string) (playback.Playbacker, error) {
+			close(entered)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	)
+	require.True(t, registry.Add("a"))
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := registry.Playback("a")
+		errs <- err
+	}()
+	<-entered
+
+	assert.True(t, registry.Has("a"), "starting should not lock the registry")
+	assert.True(t, registry.Remove("a"))
+	assert.Error(t, <-errs, "removing should cancel the start")
+	assert.False(t, registry.Has("a"))
+}
+
+func TestRegistry_TimeHandler(t *testing.T) {
+	t.Parallel()
+
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(context.Context, string) (playback.Playbacker, error) {
+			return &playback.Playback{}, nil
+		},
+	)
+	require.True(t, registry.Add("a"))
+
+	first, err := registry.TimeHandler("a")
+	require.NoError(t, err)
+	second, err := registry.TimeHandler("a")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+
+	pb, err := registry.Playback("a")
+	require.NoError(t, err)
+	assert.Same(t, pb, first.Playback)
+
+	require.True(t, registry.Remove("a"))
+	_, err = registry.TimeHandler("a")
+	require.ErrorIs(t, err, apppkg.ErrStreamNotFound)
+
+	require.True(t, registry.Add("a"))
+	third, err := registry.TimeHandler("a")
+	require.NoError(t, err)
+	assert.NotSame(t, first, third, "removing should drop the time handler")
+}
+
+func TestRegistry_WithPlayback_NotFound(t *testing.T) {
+	t.Parallel()
+
+	registry := apppkg.NewRegistry(context.Background(), nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		apppkg.StreamPathPrefix+apppkg.InfoPath,
+		apppkg.WithError(registry.WithPlayback(
+			func(_ playback.Playbacker) func(http.ResponseWriter, *http.Request) error {
+				return func(http.ResponseWriter, *http.Request) error { return nil }
+			},
+		)),
+	)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown/info", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRegistry_WithDefaultStream(t *testing.T) {
+	t.Parallel()
+
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(_ context.Context, _ string) (playback.Playbacker, error) {
+			return &playback.Playback{}, nil
+		},
+	)
+	registry.Add("a")
+	registry.Add("b")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		apppkg.StreamPathPrefix+apppkg.MPDPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, r.PathValue("videoID"), " ", r.PathValue("interval"))
+		},
+	)
+	mux.HandleFunc(apppkg.TimePath, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("time"))
+	})
+	handler := registry.WithDefaultStream("a", mux)
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/mpd/1--2", expected: "a 1--2"},
+		{path: "/a/mpd/1--2", expected: "a 1--2"},
+		{path: "/b/mpd/1--2", expected: "b 1--2"},
+		{path: "/time", expected: "time"},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.expected, w.Body.String(), tc.path)
+	}
+}
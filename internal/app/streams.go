@@ -0,0 +1,159 @@
+package app
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/xymaxim/ypb/internal/urlutil"
+)
+
+type jsonStream struct {
+	ID      string    `json:"id"`
+	Started bool      `json:"started"`
+	Info    *jsonInfo `json:"info,omitempty"`
+}
+
+// StreamsHandler manages streams of a registry.
+type StreamsHandler struct {
+	Registry *Registry
+	// Token, if set, is required as a bearer token. Otherwise, only requests
+	// from the loopback interface are accepted.
+	Token string
+}
+
+// Authorize wraps a handler of the API to reject unauthorized requests.
+func (h *StreamsHandler) Authorize(
+	next func(http.ResponseWriter, *http.Request) error,
+) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if h.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+				return &StatusError{
+					Code: http.StatusUnauthorized,
+					Err:  errors.New("missing or invalid api token"),
+				}
+			}
+		} else if !isLoopback(r.RemoteAddr) {
+			return &StatusError{
+				Code: http.StatusForbidden,
+				Err:  errors.New("api is only available locally without a token"),
+			}
+		}
+
+		// Requiring JSON keeps browsers from sending changing requests
+		// cross-site without a preflight
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !isJSON(r) {
+			return &StatusError{
+				Code: http.StatusUnsupportedMediaType,
+				Err:  errors.New("content type must be application/json"),
+			}
+		}
+
+		return next(w, r)
+	}
+}
+
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.IsLoopback()
+}
+
+// List responds with all registered streams. Information is included only for
+// started streams.
+func (h *StreamsHandler) List(w http.ResponseWriter, _ *http.Request) error {
+	streams := []jsonStream{}
+	for _, id := range h.Registry.IDs() {
+		streams = append(streams, h.describe(id))
+	}
+
+	return writeJSON(w, http.StatusOK, streams)
+}
+
+// Add registers a stream and starts its playback right away to report errors.
+func (h *StreamsHandler) Add(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("videoID")
+
+	if !urlutil.IsVideoID(id) {
+		return &StatusError{
+			Code: http.StatusBadRequest,
+			Err:  fmt.Errorf("bad video id: %q", id),
+		}
+	}
+	if !h.Registry.Add(id) {
+		return &StatusError{
+			Code: http.StatusConflict,
+			Err:  fmt.Errorf("stream already exists: %s", id),
+		}
+	}
+
+	if _, err := h.Registry.Playback(id); err != nil {
+		h.Registry.Remove(id)
+		return err
+	}
+
+	return writeJSON(w, http.StatusCreated, h.describe(id))
+}
+
+// Refresh refreshes base URLs of a stream.
+func (h *StreamsHandler) Refresh(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("videoID")
+
+	pb, err := h.Registry.Playback(id)
+	if err != nil {
+		return withNotFound(err)
+	}
+
+	if err := pb.RefreshBaseURLs(); err != nil {
+		return fmt.Errorf("refreshing base urls of %s: %w", id, err)
+	}
+
+	return writeJSON(w, http.StatusOK, h.describe(id))
+}
+
+// Remove unregisters a stream.
+func (h *StreamsHandler) Remove(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("videoID")
+
+	if !h.Registry.Remove(id) {
+		return withNotFound(fmt.Errorf("%w: %s", ErrStreamNotFound, id))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *StreamsHandler) describe(id string) jsonStream {
+	stream := jsonStream{ID: id}
+	if pb, ok := h.Registry.Started(id); ok {
+		stream.Started = true
+		stream.Info = newJSONInfo(pb.Info())
+	}
+	return stream
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("writing json response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+func TestStreamsHandler(t *testing.T) {
+	t.Parallel()
+
+	app := apppkg.NewApp()
+	require.NoError(t, app.Configure(&apppkg.Config{Port: 8080}))
+
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(_ context.Context, videoID string) (playback.Playbacker, error) {
+			if videoID == "unavailable" {
+				return nil, errors.New("unavailable")
+			}
+			return &playback.Playback{}, nil
+		},
+	)
+	registry.Add("lazy0000000")
+
+	mux := apppkg.NewMultiStreamMux(app, registry)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		r.Header.Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/streams/bad").Code)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/streams/added000000").Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/streams/added000000").Code)
+	assert.Equal(
+		t,
+		http.StatusInternalServerError,
+		do(http.MethodPost, "/api/streams/unavailable").Code,
+	)
+
+	w := do(http.MethodGet, "/api/streams")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var streams []struct {
+		ID      string `json:"id"`
+		Started bool   `json:"started"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&streams))
+	assert.Len(t, streams, 2)
+	assert.Equal(t, "added000000", streams[0].ID)
+	assert.True(t, streams[0].Started)
+	assert.Equal(t, "lazy0000000", streams[1].ID)
+	assert.False(t, streams[1].Started)
+
+	const lazyPath = "/api/streams/lazy0000000"
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, lazyPath).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, lazyPath).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/lazy0000000/info").Code)
+}
+
+func TestStreamsHandler_Authorize(t *testing.T) {
+	t.Parallel()
+
+	registry := apppkg.NewRegistry(context.Background(), nil)
+	ok := func(w http.ResponseWriter, _ *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	testCases := []struct {
+		name        string
+		method      string
+		token       string
+		remoteAddr  string
+		header      string
+		contentType string
+		expected    int
+	}{
+		{
+			name:       "local request without token",
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "local IPv6 request without token",
+			remoteAddr: "[::1]:1234",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "remote request without token",
+			remoteAddr: "192.0.2.1:1234",
+			expected:   http.StatusForbidden,
+		},
+		{
+			name:       "remote request with token",
+			token:      "secret",
+			remoteAddr: "192.0.2.1:1234",
+			header:     "Bearer secret",
+			expected:   http.StatusOK,
+		},
+		{
+			name:       "local request with wrong token",
+			token:      "secret",
+			remoteAddr: "127.0.0.1:1234",
+			header:     "Bearer wrong",
+			expected:   http.StatusUnauthorized,
+		},
+		{
+			name:       "local request with missing token",
+			token:      "secret",
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusUnauthorized,
+		},
+		{
+			name:        "local post with json",
+			method:      http.MethodPost,
+			remoteAddr:  "127.0.0.1:1234",
+			contentType: "application/json; charset=utf-8",
+			expected:    http.StatusOK,
+		},
+		{
+			name:        "local post without json",
+			method:      http.MethodPost,
+			remoteAddr:  "127.0.0.1:1234",
+			contentType: "text/plain",
+			expected:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "local post without content type",
+			method:     http.MethodPost,
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "local delete without content type",
+			method:     http.MethodDelete,
+			remoteAddr: "127.0.0.1:1234",
+			expected:   http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &apppkg.StreamsHandler{Registry: registry, Token: tc.token}
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/api/streams", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			apppkg.WithError(h.Authorize(ok)).ServeHTTP(w, r)
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback"
+)
+
+// clockSyncInterval is how long a measured stream clock offset is reused.
+const clockSyncInterval = time.Minute
+
+// TimeHandler responds with the current time in ISO 8601, referenced by
+// UTCTiming elements of manifests for players to synchronize their clocks.
+//
+// If Playback is set, the time follows the stream clock rather than the local
+// one: it is offset by the difference between the end of the head segment and
+// the local time the segment is seen at.
+type TimeHandler struct {
+	Playback playback.Playbacker
+
+	mu       sync.Mutex
+	offset   time.Duration
+	syncedAt time.Time
+}
+
+func (h *TimeHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) error {
+	now := time.Now()
+
+	if h.Playback != nil {
+		offset, err := h.clockOffset(now)
+		if err != nil {
+			return fmt.Errorf("measuring stream clock offset: %w", err)
+		}
+		now = now.Add(offset)
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := fmt.Fprint(w, now.UTC().Format("2006-01-02T15:04:05.000Z")); err != nil {
+		return fmt.Errorf("writing time: %w", err)
+	}
+
+	return nil
+}
+
+// clockOffset returns the offset of the stream clock from the local one,
+// measuring it again if the last measurement is outdated.
+func (h *TimeHandler) clockOffset(now time.Time) (time.Duration, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.syncedAt.IsZero() && now.Sub(h.syncedAt) < clockSyncInterval {
+		return h.offset, nil
+	}
+
+	sq, err := h.Playback.RequestHeadSeqNum()
+	if err != nil {
+		return 0, fmt.Errorf("requesting head segment: %w", err)
+	}
+	head, err := h.Playback.FetchSegmentMetadata(h.Playback.ProbeItag(), sq)
+	if err != nil {
+		return 0, playback.NewSegmentMetadataFetchError(sq, err)
+	}
+
+	seenAt := time.Now()
+	h.offset = head.EndTime().Sub(seenAt)
+	h.syncedAt = seenAt
+
+	return h.offset, nil
+}
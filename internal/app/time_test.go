@@ -0,0 +1,79 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func serveTime(t *testing.T, h *apppkg.TimeHandler) time.Time {
+	t.Helper()
+	w := httptest.NewRecorder()
+	apppkg.WithError(h.ServeHTTP)(w, httptest.NewRequest(http.MethodGet, "/time", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	got, err := time.Parse(time.RFC3339, w.Body.String())
+	require.NoError(t, err)
+	return got
+}
+
+func TestTimeHandler(t *testing.T) {
+	t.Parallel()
+	assert.WithinDuration(t, time.Now(), serveTime(t, &apppkg.TimeHandler{}), time.Second)
+}
+
+func TestTimeHandler_StreamClock(t *testing.T) {
+	t.Parallel()
+
+	const head = 99
+	data := testutil.GenerateFakeSegmentMetadata(head+1, 2*time.Second)
+	upstream := newUpstream(t, data, head)
+	defer upstream.Close()
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(upstream.URL),
+	)
+	require.NoError(t, err)
+
+	h := &apppkg.TimeHandler{Playback: pb}
+	headSegment := data[head]
+	assert.WithinDuration(t, headSegment.EndTime(), serveTime(t, h), time.Second)
+	// The measured offset is reused
+	assert.WithinDuration(t, headSegment.EndTime(), serveTime(t, h), time.Second)
+}
+
+func TestMultiStreamMux_LocalTime(t *testing.T) {
+	t.Parallel()
+
+	app := apppkg.NewApp()
+	require.NoError(t, app.Configure(&apppkg.Config{Port: 8080}))
+
+	registry := apppkg.NewRegistry(
+		context.Background(),
+		func(context.Context, string) (playback.Playbacker, error) {
+			t.Error("playback should not be started for the local clock")
+			return &playback.Playback{}, nil
+		},
+	)
+	registry.Add(testutil.TestVideoID)
+	mux := apppkg.NewMultiStreamMux(app, registry)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+testutil.TestVideoID+"/time", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown0000/time", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -13,6 +13,9 @@ import (
 
 type Frame struct {
 	commands.CommonFlags
+	commands.VideoFilterFlags
+	commands.MetadataFlags
+	commands.OutputFlags
 	CommonCaptureFlags
 	Moment string `help:"Moment to capture" required:"" short:"m"`
 	Stream string `help:"YouTube video ID"  required:""           arg:""`
@@ -21,22 +24,50 @@ type Frame struct {
 type FrameConfig struct {
 	MomentValue  any
 	OutputFormat string
+	Template     *commands.OutputTemplate
 	OutputPath   string
 }
 
-func (c *Frame) Run() error {
+// frameTemplateFields are fields of output templates of frames.
+var frameTemplateFields = []string{
+	commands.FieldTitle,
+	commands.FieldID,
+	commands.FieldChannel,
+	commands.FieldStart,
+	commands.FieldEnd,
+	commands.FieldSq,
+	commands.FieldExt,
+}
+
+func (c *Frame) Run(tz *commands.Timezone) error {
 	pinnedTime := time.Now().UTC()
 
+	if err := c.CheckFetcher(tz); err != nil {
+		return err
+	}
+
 	app := apppkg.NewApp()
 
 	// Parse and validate inputs
-	config, err := c.parseAndValidateInputs()
+	config, err := c.parseAndValidateInputs(tz)
 	if err != nil {
 		return err
 	}
 
 	// Collect video information and initialize the app
-	if err := commands.CollectVideoInfo(c.Stream, app, c.Port); err != nil {
+	if err := commands.CollectVideoInfo(c.Stream, app, c.CommonFlags.Config(), tz); err != nil {
+		return err
+	}
+	if tz.FollowsStream() {
+		// Times are read again once the stream's time zone is known
+		if config, err = c.parseAndValidateInputs(tz); err != nil {
+			return err
+		}
+	}
+	if err := commands.FilterStreams(app, c.Filter()); err != nil {
+		return err
+	}
+	if err := commands.RequireVideo(app); err != nil {
 		return err
 	}
 
@@ -53,19 +84,36 @@ func (c *Frame) Run() error {
 
 	fmt.Printf(
 		"Frame to be captured: %s, sq=%d\n",
-		rewindMoment.TargetTime.Format(time.RFC1123Z),
+		tz.In(rewindMoment.TargetTime).Format(time.RFC1123Z),
 		rewindMoment.Metadata.SequenceNumber,
 	)
 
 	// Capture the frame
-	config.OutputPath = fmt.Sprintf(
-		"%s_%s_%s.%s",
-		commands.AdjustForFilename(app.Playback.Info().Title, 0),
-		app.Playback.Info().ID,
-		commands.FormatTime(rewindMoment.TargetTime),
-		c.OutputFormat,
+	outputPath, ok, err := c.Namer().Resolve(config.Template.Execute(commands.OutputFields{
+		Title:   app.Playback.Info().Title,
+		ID:      app.Playback.Info().ID,
+		Channel: app.Playback.Info().ChannelTitle,
+		Start:   rewindMoment.TargetTime,
+		End:     rewindMoment.TargetTime,
+		Sq:      rewindMoment.Metadata.SequenceNumber,
+		Ext:     c.OutputFormat,
+	}))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Output already exists, skipping")
+		return nil
+	}
+	config.OutputPath = outputPath
+
+	err = actions.CaptureFrame(
+		app.Playback,
+		rewindMoment,
+		config.OutputPath,
+		c.FrameMetadata(app),
+		app.FFmpegRunner,
 	)
-	err = actions.CaptureFrame(app.Playback, rewindMoment, config.OutputPath, app.FFmpegRunner)
 	if err != nil {
 		return fmt.Errorf("capturing frame: %w", err)
 	}
@@ -75,15 +123,21 @@ func (c *Frame) Run() error {
 	return nil
 }
 
-func (c *Frame) parseAndValidateInputs() (*FrameConfig, error) {
-	momentValue, err := input.ParseIntervalPart(c.Moment)
+func (c *Frame) parseAndValidateInputs(tz *commands.Timezone) (*FrameConfig, error) {
+	momentValue, err := input.ParseIntervalPartIn(c.Moment, tz.Location())
 	if err != nil {
 		return nil, fmt.Errorf("parsing input moment: %w", err)
 	}
 
+	template, err := c.Template(tz, commands.DefaultFrameTemplate, frameTemplateFields...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &FrameConfig{
 		MomentValue:  momentValue,
 		OutputFormat: c.OutputFormat,
+		Template:     template,
 	}, nil
 }
 
@@ -3,8 +3,8 @@ package capture
 import (
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +21,9 @@ import (
 
 type Timelapse struct {
 	commands.CommonFlags
+	commands.VideoFilterFlags
+	commands.MetadataFlags
+	commands.OutputFlags
 	CommonCaptureFlags
 	Every    string `help:"Capture frame every duration" placeholder:"DURATION" required:"" short:"e"`
 	Stream   string `help:"YouTube video ID"                                    required:""           arg:""`
@@ -28,24 +31,53 @@ type Timelapse struct {
 }
 
 type TimelapseConfig struct {
-	StartMoment   input.MomentValue
-	EndMoment     input.MomentValue
-	CaptureEvery  time.Duration
-	OutputFormat  string
-	OutputPattern string
+	StartMoment  input.MomentValue
+	EndMoment    input.MomentValue
+	CaptureEvery time.Duration
+	OutputFormat string
+	Template     *commands.OutputTemplate
 }
 
-func (c *Timelapse) Run() error {
+// timelapseTemplateFields are fields of output templates of timelapse frames.
+var timelapseTemplateFields = []string{
+	commands.FieldTitle,
+	commands.FieldID,
+	commands.FieldChannel,
+	commands.FieldStart,
+	commands.FieldEnd,
+	commands.FieldDuration,
+	commands.FieldFrame,
+	commands.FieldEvery,
+	commands.FieldExt,
+}
+
+func (c *Timelapse) Run(tz *commands.Timezone) error {
 	pinnedTime := time.Now().UTC()
 
+	if err := c.CheckFetcher(tz); err != nil {
+		return err
+	}
+
 	app := apppkg.NewApp()
 
-	config, err := c.parseAndValidateInputs()
+	config, err := c.parseAndValidateInputs(tz)
 	if err != nil {
 		return err
 	}
 
-	if err := commands.CollectVideoInfo(c.Stream, app, c.Port); err != nil {
+	if err := commands.CollectVideoInfo(c.Stream, app, c.CommonFlags.Config(), tz); err != nil {
+		return err
+	}
+	if tz.FollowsStream() {
+		// Times are read again once the stream's time zone is known
+		if config, err = c.parseAndValidateInputs(tz); err != nil {
+			return err
+		}
+	}
+	if err := commands.FilterStreams(app, c.Filter()); err != nil {
+		return err
+	}
+	if err := commands.RequireVideo(app); err != nil {
 		return err
 	}
 
@@ -60,13 +92,7 @@ func (c *Timelapse) Run() error {
 		config.CaptureEvery,
 	)
 
-	printCapturePlan(captureTimes, config.CaptureEvery)
-
-	config.OutputPattern = c.buildOutputPattern(app, captureTimes, config)
-	outputDirectory := filepath.Dir(config.OutputPattern)
-	if err := os.Mkdir(outputDirectory, os.ModePerm); err != nil {
-		return fmt.Errorf("creating output directories: %w", err)
-	}
+	printCapturePlan(tz, captureTimes, config.CaptureEvery)
 
 	err = c.captureFrames(app, captureTimes, locateContext, config)
 	if err != nil {
@@ -76,14 +102,10 @@ func (c *Timelapse) Run() error {
 	return nil
 }
 
-func (c *Timelapse) parseAndValidateInputs() (*TimelapseConfig, error) {
-	start, end, err := input.ParseInterval(c.Interval)
+func (c *Timelapse) parseAndValidateInputs(tz *commands.Timezone) (*TimelapseConfig, error) {
+	start, end, err := commands.ParseInterval(c.Interval, tz)
 	if err != nil {
-		return nil, fmt.Errorf("parsing input interval: %w", err)
-	}
-
-	if err := input.ValidateMoments(start, end); err != nil {
-		return nil, fmt.Errorf("bad input interval: %w", err)
+		return nil, err
 	}
 
 	duration, err := input.ParseIntervalPart(c.Every)
@@ -96,11 +118,20 @@ func (c *Timelapse) parseAndValidateInputs() (*TimelapseConfig, error) {
 		return nil, errors.New("every duration must be a time.Duration")
 	}
 
+	template, err := c.Template(tz, commands.DefaultTimelapseTemplate, timelapseTemplateFields...)
+	if err != nil {
+		return nil, err
+	}
+	if !template.Uses(commands.FieldFrame) {
+		return nil, errors.New("output template must contain the {frame} field")
+	}
+
 	return &TimelapseConfig{
 		StartMoment:  start,
 		EndMoment:    end,
 		CaptureEvery: captureEvery,
 		OutputFormat: c.OutputFormat,
+		Template:     template,
 	}, nil
 }
 
@@ -139,22 +170,23 @@ func (c *Timelapse) calculateCaptureTimes(start, end time.Time, every time.Durat
 	return times
 }
 
-func (c *Timelapse) buildOutputPattern(
+// outputFields returns fields of output names of frames captured at the times.
+func (c *Timelapse) outputFields(
 	app *apppkg.App,
-	captureTimes []time.Time,
+	times []time.Time,
 	config *TimelapseConfig,
-) string {
-	basename := fmt.Sprintf(
-		"%s_%s_%s_e%s",
-		commands.AdjustForFilename(app.Playback.Info().Title, 0),
-		app.Playback.Info().ID,
-		commands.FormatTime(captureTimes[0]),
-		commands.FormatDuration(config.CaptureEvery),
-	)
-	outputDirectory := basename
-	outputFilename := fmt.Sprintf("%s_%%04d.%s", basename, config.OutputFormat)
-
-	return filepath.Join(outputDirectory, outputFilename)
+) commands.OutputFields {
+	start, end := times[0], times[len(times)-1]
+	return commands.OutputFields{
+		Title:    app.Playback.Info().Title,
+		ID:       app.Playback.Info().ID,
+		Channel:  app.Playback.Info().ChannelTitle,
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start),
+		Every:    config.CaptureEvery,
+		Ext:      config.OutputFormat,
+	}
 }
 
 func (c *Timelapse) captureFrames(
@@ -163,7 +195,33 @@ func (c *Timelapse) captureFrames(
 	locateContext *actions.LocateContext,
 	config *TimelapseConfig,
 ) error {
-	fmt.Printf("(<<) Capturing frames to '%s'...\n", filepath.Dir(config.OutputPattern))
+	// Collisions are resolved for all frames at once, so that they are named
+	// alike
+	fields := c.outputFields(app, times, config)
+	paths := make([]string, len(times))
+	for i := range times {
+		fields.Frame = i
+		paths[i] = config.Template.Execute(fields)
+	}
+	// Frames share the template's directory unless it depends on the frame
+	dir := config.Template.OwnDir(fields)
+	fields.Frame = 0
+	if config.Template.OwnDir(fields) != dir {
+		dir = ""
+	}
+	paths, err := c.Namer().ResolveGroup(paths, dir)
+	if err != nil {
+		return err
+	}
+	outputPath := func(index int) (string, error) {
+		return paths[index], nil
+	}
+
+	outputDirectory := filepath.Dir(config.Template.Execute(fields))
+	if i := slices.IndexFunc(paths, func(path string) bool { return path != "" }); i >= 0 {
+		outputDirectory = filepath.Dir(paths[i])
+	}
+	fmt.Printf("(<<) Capturing frames to '%s'...\n", outputDirectory)
 
 	start := time.Now()
 	totalFrames := len(times)
@@ -192,11 +250,16 @@ func (c *Timelapse) captureFrames(
 		app.Playback,
 		times,
 		locateContext,
-		config.OutputPattern,
+		outputPath,
+		c.FrameMetadata(app),
 		app.FFmpegRunner,
 		onFrame,
 	)
 	if err != nil {
+		fmt.Printf(
+			"%d of %d frames captured (%d skipped)\n",
+			captured, len(times), skipped,
+		)
 		return fmt.Errorf("capturing frames: %w", err)
 	}
 
@@ -208,7 +271,7 @@ func (c *Timelapse) captureFrames(
 	return nil
 }
 
-func printCapturePlan(times []time.Time, duration time.Duration) {
+func printCapturePlan(tz *commands.Timezone, times []time.Time, duration time.Duration) {
 	total := len(times)
 
 	frameWord := "frames"
@@ -223,7 +286,7 @@ func printCapturePlan(times []time.Time, duration time.Duration) {
 	)
 
 	formatTime := func(t time.Time) string {
-		return t.Format(time.RFC1123Z)
+		return tz.In(t).Format(time.RFC1123Z)
 	}
 	if total <= 3 {
 		for i := range total {
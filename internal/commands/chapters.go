@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/input"
+)
+
+// chapterSeparator separates the time and title of a chapter.
+const chapterSeparator = "|"
+
+// parseChapter parses a chapter given as 'time|title'. The time is a moment
+// value or a duration from the start of the interval. Without a title, the
+// chapter is titled by its number. Times without an offset are read in the
+// location.
+func parseChapter(s string, number int, loc *time.Location) (actions.ChapterMark, error) {
+	rawTime, title, _ := strings.Cut(s, chapterSeparator)
+
+	value, err := input.ParseIntervalPartIn(strings.TrimSpace(rawTime), loc)
+	if err != nil {
+		return actions.ChapterMark{}, fmt.Errorf("parsing chapter time: %w", err)
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = fmt.Sprintf("Chapter %d", number)
+	}
+
+	return actions.ChapterMark{Value: value, Title: title}, nil
+}
+
+// loadChapters reads chapters from a file with a chapter per line, given as
+// 'time|title'. Blank lines and lines starting with '#' are skipped.
+func loadChapters(path string) ([]string, error) {
+	b, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading chapters file: %w", err)
+	}
+
+	var chapters []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		chapters = append(chapters, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading chapters file: %w", err)
+	}
+
+	if len(chapters) == 0 {
+		return nil, errors.New("no chapters in chapters file")
+	}
+
+	return chapters, nil
+}
+
+// chapterMarks returns chapter marks given with the chapter flags, followed by
+// the ones from the chapters file.
+func (c *Download) chapterMarks() ([]actions.ChapterMark, error) {
+	chapters := c.Chapter
+	if c.ChaptersFile != "" {
+		fromFile, err := loadChapters(c.ChaptersFile)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, fromFile...)
+	}
+
+	marks := make([]actions.ChapterMark, 0, len(chapters))
+	for i, s := range chapters {
+		mark, err := parseChapter(s, i+1, c.tz.Location())
+		if err != nil {
+			return nil, err
+		}
+		marks = append(marks, mark)
+	}
+
+	return marks, nil
+}
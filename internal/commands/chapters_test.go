@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChapter(t *testing.T) {
+	t.Parallel()
+
+	mark, err := parseChapter("2026-01-02T10:20:30+00 | Intro | Part 1", 1, time.UTC)
+	require.NoError(t, err)
+	value, ok := mark.Value.(time.Time)
+	require.True(t, ok)
+	assert.True(t, value.Equal(time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)))
+	assert.Equal(t, "Intro | Part 1", mark.Title)
+
+	mark, err = parseChapter("90s", 2, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, mark.Value)
+	assert.Equal(t, "Chapter 2", mark.Title)
+
+	_, err = parseChapter("bad|Title", 3, time.UTC)
+	require.Error(t, err)
+}
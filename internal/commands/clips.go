@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/input"
+)
+
+// clip is an interval of a stream to download, with an optional title used
+// in place of the stream title in the output name.
+type clip struct {
+	Interval string `json:"interval"`
+	Title    string `json:"title,omitempty"`
+
+	start input.MomentValue
+	end   input.MomentValue
+}
+
+// loadClips reads clips from a cue file. The format follows the extension:
+// a JSON array of objects for .json, rows of an interval and an optional title
+// for .csv, and, otherwise, lines of an interval optionally followed by a tab
+// and a title. Blank lines and lines starting with '#' are skipped in the
+// last format. Times without an offset are read in the location.
+func loadClips(path string, loc *time.Location) ([]clip, error) {
+	b, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading clips file: %w", err)
+	}
+
+	var clips []clip
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		clips, err = parseJSONClips(b)
+	case ".csv":
+		clips, err = parseCSVClips(b)
+	default:
+		clips, err = parseTextClips(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing clips file: %w", err)
+	}
+
+	if len(clips) == 0 {
+		return nil, errors.New("no clips in clips file")
+	}
+
+	for i := range clips {
+		c := &clips[i]
+		c.Title = strings.TrimSpace(c.Title)
+		c.start, c.end, err = input.ParseIntervalIn(strings.TrimSpace(c.Interval), loc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing interval of clip %d: %w", i+1, err)
+		}
+		if err := input.ValidateMoments(c.start, c.end); err != nil {
+			return nil, fmt.Errorf("bad interval of clip %d: %w", i+1, err)
+		}
+	}
+
+	return clips, nil
+}
+
+func parseJSONClips(b []byte) ([]clip, error) {
+	var clips []clip
+	if err := json.Unmarshal(b, &clips); err != nil {
+		return nil, fmt.Errorf("decoding JSON: %w", err)
+	}
+	return clips, nil
+}
+
+func parseCSVClips(b []byte) ([]clip, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+
+	var clips []clip
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %w", err)
+		}
+		c := clip{Interval: record[0]}
+		if len(record) > 1 {
+			c.Title = record[1]
+		}
+		clips = append(clips, c)
+	}
+
+	return clips, nil
+}
+
+func parseTextClips(b []byte) ([]clip, error) {
+	var clips []clip
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		interval, title, _ := strings.Cut(line, "\t")
+		clips = append(clips, clip{Interval: interval, Title: title})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading lines: %w", err)
+	}
+
+	return clips, nil
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadClips(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "clips.txt",
+			content: "# Clips\n" +
+				"2026-01-02T10:20:30+00/30s\tFirst clip\n" +
+				"\n" +
+				"100--120\n",
+		},
+		{
+			name: "clips.csv",
+			content: "2026-01-02T10:20:30+00/30s,First clip\n" +
+				"100--120\n",
+		},
+		{
+			name: "clips.json",
+			content: `[
+				{"interval": "2026-01-02T10:20:30+00/30s", "title": "First clip"},
+				{"interval": "100--120"}
+			]`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tc.name)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			clips, err := loadClips(path, time.UTC)
+			require.NoError(t, err)
+			require.Len(t, clips, 2)
+			assert.Equal(t, "First clip", clips[0].Title)
+			assert.NotNil(t, clips[0].start)
+			assert.NotNil(t, clips[0].end)
+			assert.Empty(t, clips[1].Title)
+		})
+	}
+}
+
+func TestLoadClips_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "empty.txt")
+	require.NoError(t, os.WriteFile(empty, []byte("# Nothing\n"), 0o600))
+	_, err := loadClips(empty, time.UTC)
+	require.ErrorContains(t, err, "no clips")
+
+	bad := filepath.Join(dir, "bad.txt")
+	require.NoError(t, os.WriteFile(bad, []byte("100--120\nbad\n"), 0o600))
+	_, err = loadClips(bad, time.UTC)
+	require.ErrorContains(t, err, "clip 2")
+}
@@ -4,17 +4,134 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gosimple/slug"
 
+	"github.com/xymaxim/ypb/internal/actions"
 	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/input"
+	"github.com/xymaxim/ypb/internal/playback/fetchers"
+	"github.com/xymaxim/ypb/internal/playback/info"
 	"github.com/xymaxim/ypb/internal/urlutil"
 )
 
 type CommonFlags struct {
-	Port int `help:"Port to start playback on" short:"p" default:"8080"`
+	Port          int      `help:"Port to start playback on"                                  short:"p" default:"8080"`
+	CacheDir      string   `help:"Directory to cache segment metadata in"                                                type:"path"`
+	Fetcher       string   `help:"Fetcher of stream info: ${enum}"                                      default:"yt-dlp" enum:"${fetchers}"`
+	FetcherSource []string `help:"Info file for the file fetcher, or base URLs for the static one"                         sep:"none"`
+}
+
+// PrefetchFlags are flags of commands streaming consecutive segments.
+type PrefetchFlags struct {
+	Prefetch int `help:"Number of segments to prefetch after each requested one" default:"0"`
+}
+
+// VideoFilterFlags are flags restricting video streams of commands.
+type VideoFilterFlags struct {
+	Itags     []string `help:"Only use streams with the itags"`
+	MaxHeight int      `help:"Only use video streams up to the height"`
+}
+
+// FilterFlags are flags restricting streams of commands.
+type FilterFlags struct {
+	VideoFilterFlags
+	Video string `help:"Video streams to use: ${enum}" enum:"all,best,none" default:"all"`
+	Audio string `help:"Audio streams to use: ${enum}" enum:"all,best,none" default:"all"`
+}
+
+// MetadataFlags are flags of provenance metadata of output files.
+type MetadataFlags struct {
+	EmbedMetadata bool `help:"Embed provenance metadata into output files"`
+	Sidecar       bool `help:"Write provenance metadata to a JSON file next to each output"`
+}
+
+// FrameMetadata returns the metadata to write for frames captured from the
+// app playback.
+func (f *MetadataFlags) FrameMetadata(app *apppkg.App) *actions.FrameMetadata {
+	if !f.EmbedMetadata && !f.Sidecar {
+		return nil
+	}
+
+	var itags []string
+	if video := app.Playback.Info().BestVideo(); video != nil {
+		itags = []string{video.Itag}
+	}
+	metadata := &actions.FrameMetadata{
+		Provenance: actions.NewProvenance(app.Playback.Info(), itags),
+		Embed:      f.EmbedMetadata,
+	}
+	if f.Sidecar {
+		metadata.SidecarPath = SidecarPath
+	}
+
+	return metadata
+}
+
+// SidecarPath returns the path of a sidecar file with provenance metadata of
+// an output.
+func SidecarPath(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".json"
+}
+
+// Filter returns the stream filter from flags.
+func (f *VideoFilterFlags) Filter() info.StreamFilter {
+	return info.StreamFilter{Itags: f.Itags, MaxHeight: f.MaxHeight}
+}
+
+// Filter returns the stream filter from flags.
+func (f *FilterFlags) Filter() info.StreamFilter {
+	filter := f.VideoFilterFlags.Filter()
+	filter.Video, filter.Audio = f.Video, f.Audio
+	return filter
+}
+
+// FilterStreams restricts streams of the app playback.
+func FilterStreams(app *apppkg.App, filter info.StreamFilter) error {
+	pb, err := actions.FilterStreams(app.Playback, filter)
+	if err != nil {
+		return fmt.Errorf("filtering streams: %w", err)
+	}
+	app.Playback = pb
+	return nil
+}
+
+// RequireVideo checks that the app playback has a video stream to capture
+// frames from, which filters may leave out.
+func RequireVideo(app *apppkg.App) error {
+	if app.Playback.Info().BestVideo() == nil {
+		return actions.ErrNoVideoStreams
+	}
+	return nil
+}
+
+// Config returns the app config from flags.
+func (f *CommonFlags) Config() *apppkg.Config {
+	return &apppkg.Config{
+		Port:          f.Port,
+		CacheDir:      f.CacheDir,
+		Fetcher:       f.Fetcher,
+		FetcherSource: f.FetcherSource,
+	}
+}
+
+// CheckFetcher checks that the selected fetcher can be used, also with the
+// time zone: only stream descriptions read by the file fetcher tell the
+// stream's time zone. Without a selected fetcher, yt-dlp is used.
+func (f *CommonFlags) CheckFetcher(tz *Timezone) error {
+	if tz.FollowsStream() && f.Fetcher != fetchers.FileName {
+		return fmt.Errorf(
+			"stream time zone is only known with the %s fetcher, not %s",
+			fetchers.FileName, f.Fetcher,
+		)
+	}
+	if f.Fetcher == "" || f.Fetcher == fetchers.YtdlpName {
+		return checkYtdlp()
+	}
+	return nil
 }
 
 func checkYtdlp() error {
@@ -25,18 +142,32 @@ func checkYtdlp() error {
 	return nil
 }
 
-func CollectVideoInfo(id string, app *apppkg.App, port int) error {
+// ParseInterval parses and validates an input interval, reading times without
+// an offset in the time zone.
+func ParseInterval(s string, tz *Timezone) (input.MomentValue, input.MomentValue, error) {
+	start, end, err := input.ParseIntervalIn(s, tz.Location())
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing input interval: %w", err)
+	}
+	if err := input.ValidateMoments(start, end); err != nil {
+		return nil, nil, fmt.Errorf("bad input interval: %w", err)
+	}
+	return start, end, nil
+}
+
+// CollectVideoInfo initializes the app with the stream and switches to the
+// stream's time zone, if requested.
+func CollectVideoInfo(id string, app *apppkg.App, cfg *apppkg.Config, tz *Timezone) error {
 	url := urlutil.BuildVideoLiveURL(id)
 
 	fmt.Printf("(<<) Collecting info about %s...\n", url)
-	cfg := &apppkg.Config{Port: port}
 	if err := app.Initialize(context.Background(), id, cfg); err != nil {
 		return fmt.Errorf("initializing app: %w", err)
 	}
 
 	fmt.Printf("Stream '%s' is alive!\n", app.Playback.Info().Title)
 
-	return nil
+	return tz.UseStream(app.Playback.Info())
 }
 
 func AdjustForFilename(s string, length int) string {
@@ -52,7 +183,12 @@ func AdjustForFilename(s string, length int) string {
 	return slug.Make(s)
 }
 
+// FormatTime formats the time for filenames. The offset minutes are kept only
+// if not zero.
 func FormatTime(t time.Time) string {
+	if _, offset := t.Zone(); offset%3600 != 0 {
+		return t.Format("20060102T150405-0700")
+	}
 	return t.Format("20060102T150405-07")
 }
 
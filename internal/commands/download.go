@@ -2,47 +2,110 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xymaxim/ypb/internal/actions"
 	apppkg "github.com/xymaxim/ypb/internal/app"
 	"github.com/xymaxim/ypb/internal/input"
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/info"
 	"github.com/xymaxim/ypb/internal/urlutil"
 )
 
+// ytdlpExtPlaceholder is the extension placeholder of yt-dlp output templates,
+// used when the extension is chosen by yt-dlp.
+const ytdlpExtPlaceholder = "%(ext)s"
+
+// downloadTemplateFields are fields of output templates of downloads.
+var downloadTemplateFields = []string{
+	FieldTitle, FieldID, FieldChannel, FieldStart, FieldEnd, FieldDuration, FieldSq, FieldExt,
+}
+
 type Download struct {
 	CommonFlags
-	Stream       string   `arg:"" help:"YouTube video ID"                         required:""`
-	Interval     string   `       help:"Time or segment interval"                 required:"" short:"i"`
+	PrefetchFlags
+	FilterFlags
+	MetadataFlags
+	OutputFlags
+	Stream       string   `arg:"" help:"YouTube video ID"                         optional:""`
+	Interval     string   `       help:"Time or segment interval"                             short:"i"                             xor:"interval"`
+	Clips        string   `       help:"Download clips listed in a file (text, CSV, or JSON)"           type:"existingfile"        xor:"interval"`
+	Native       bool     `       help:"Download and merge media without yt-dlp"`
+	AudioOnly    bool     `       help:"Download only the best audio stream"                                                                  xor:"tracks"`
+	VideoOnly    bool     `       help:"Download only the best video stream"                                                                  xor:"tracks"`
+	Format       string   `       help:"Itags of streams to download, joined with '+' (e.g., 137+140)"                                        xor:"tracks"`
+	Chapter      []string `       help:"Chapter at a moment or offset from the start, as 'time|title' (repeatable)"                    sep:"none"`
+	ChaptersFile string   `       help:"File with a chapter per line, as 'time|title'"                 name:"chapters"      type:"existingfile"`
+	Resume       string   `       help:"Resume an interrupted download from its state file"           type:"existingfile"`
 	YtdlpOptions []string `arg:"" help:"Options to pass to yt-dlp (use after --)"                       optional:"" passthrough:""` //nolint:lll
+
+	tz *Timezone
 }
 
-func (c *Download) Run() error {
+// downloadInputs are moments parsed from the inputs of a download.
+type downloadInputs struct {
+	marks []actions.ChapterMark
+	start input.MomentValue
+	end   input.MomentValue
+	clips []clip
+}
+
+func (c *Download) Run(tz *Timezone) error {
 	pinnedTime := time.Now().UTC()
+	c.tz = tz
 
-	if err := checkYtdlp(); err != nil {
+	if c.Resume != "" {
+		return c.resume()
+	}
+
+	if err := c.CheckFetcher(tz); err != nil {
 		return err
 	}
 
-	app := apppkg.NewApp()
+	if c.Stream == "" || (c.Interval == "" && c.Clips == "") {
+		return errors.New("stream and interval (or clips) are required unless resuming")
+	}
+	if c.Native && len(c.YtdlpOptions) > 0 {
+		return errors.New("yt-dlp options are not supported with --native")
+	}
 
-	start, end, err := input.ParseInterval(c.Interval)
+	inputs, err := c.parseInputs()
 	if err != nil {
-		return fmt.Errorf("parsing input interval: %w", err)
+		return err
 	}
-	if err := input.ValidateMoments(start, end); err != nil {
-		return fmt.Errorf("bad input interval: %w", err)
+
+	template, err := c.Template(tz, DefaultDownloadTemplate, downloadTemplateFields...)
+	if err != nil {
+		return err
 	}
+	namer := c.Namer()
 
-	if err := CollectVideoInfo(c.Stream, app, c.Port); err != nil {
+	app, filter, err := c.newApp()
+	if err != nil {
 		return err
 	}
+	if tz.FollowsStream() {
+		// Times are read again once the stream's time zone is known
+		if inputs, err = c.parseInputs(); err != nil {
+			return err
+		}
+	}
+
+	if c.Clips != "" {
+		return c.downloadClips(app, filter, pinnedTime, inputs, template, namer)
+	}
 
 	fmt.Println("(<<) Locating start and end moments...")
 	locateContext, err := actions.NewLocateContext(app.Playback, nil, &pinnedTime)
@@ -52,68 +115,598 @@ func (c *Download) Run() error {
 
 	interval, outputContext, err := actions.LocateInterval(
 		app.Playback,
-		start,
-		end,
+		inputs.start,
+		inputs.end,
 		locateContext,
 	)
 	if err != nil {
 		return fmt.Errorf("locating interval: %w", err)
 	}
 
-	fmt.Println(formatActualLine("start", interval.Start))
-	fmt.Println(" ", formatActualLine("end", interval.End))
+	fmt.Println(formatActualLine(tz, "start", interval.Start))
+	fmt.Println(" ", formatActualLine(tz, "end", interval.End))
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/mpd", apppkg.WithError(
-		func(w http.ResponseWriter, r *http.Request) error {
-			return serveMPD(w, app, interval)
-		}),
-	)
-	segmentHandler := &apppkg.SegmentHandler{
-		Playback: app.Playback,
+	chapters, err := locateChapters(app, interval, inputs.marks, locateContext)
+	if err != nil {
+		return err
 	}
-	mux.HandleFunc(apppkg.SegmentPath, apppkg.WithError(segmentHandler.ServeHTTP))
 
-	app.Server.Handler = mux
+	output, ok, err := c.outputPath(template, namer, app.Playback.Info(), outputContext)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Output already exists, skipping")
+		return nil
+	}
 
-	go func() {
-		slog.Debug("starting server", "addr", app.Server.Addr)
-		err = app.Server.ListenAndServe()
+	state := c.newState(app, filter, interval, outputContext, output)
+	state.Chapters = chapters
+
+	return (&downloader{app: app}).download(state, statePath(state.Output))
+}
+
+// parseInputs parses the interval, or the clips, and the chapters of the
+// download.
+func (c *Download) parseInputs() (*downloadInputs, error) {
+	marks, err := c.chapterMarks()
+	if err != nil {
+		return nil, err
+	}
+	inputs := &downloadInputs{marks: marks}
+
+	if c.Clips != "" {
+		inputs.clips, err = loadClips(c.Clips, c.tz.Location())
+	} else {
+		inputs.start, inputs.end, err = ParseInterval(c.Interval, c.tz)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return inputs, nil
+}
+
+// downloadClips downloads clips listed in the clips file. All clips are
+// located up front with one locate context, and a failed clip does not stop
+// downloading the others.
+func (c *Download) downloadClips(
+	app *apppkg.App,
+	filter info.StreamFilter,
+	pinnedTime time.Time,
+	inputs *downloadInputs,
+	template *OutputTemplate,
+	namer *OutputNamer,
+) error {
+	clips, marks := inputs.clips, inputs.marks
+
+	fmt.Printf("(<<) Locating %d clips...\n", len(clips))
+	locateContext, err := actions.NewLocateContext(app.Playback, nil, &pinnedTime)
+	if err != nil {
+		return fmt.Errorf("building locate context: %w", err)
+	}
+
+	var errs []error
+	failClip := func(i int, err error) {
+		slog.Error("failed to prepare clip", "clip", i+1, "err", err)
+		errs = append(errs, fmt.Errorf("clip %d: %w", i+1, err))
+	}
+
+	states := make([]*downloadState, 0, len(clips))
+	for i, clip := range clips {
+		interval, outputContext, err := actions.LocateInterval(
+			app.Playback,
+			clip.start,
+			clip.end,
+			locateContext,
+		)
 		if err != nil {
-			log.Fatal(err)
+			failClip(i, fmt.Errorf("locating interval: %w", err))
+			continue
+		}
+		if clip.Title != "" {
+			outputContext.Title = clip.Title
 		}
-	}()
+
+		fmt.Printf("Clip %d of %d: %s\n", i+1, len(clips), outputContext.Title)
+		fmt.Printf(
+			"  Requested: %s -- %s\n",
+			c.tz.In(outputContext.InputStartTime).Format(time.RFC1123Z),
+			c.tz.In(outputContext.InputEndTime).Format(time.RFC1123Z),
+		)
+		fmt.Println(" ", formatActualLine(c.tz, "start", interval.Start))
+		fmt.Println("   ", formatActualLine(c.tz, "end", interval.End))
+
+		chapters, err := locateChapters(app, interval, marks, locateContext)
+		if err != nil {
+			failClip(i, err)
+			continue
+		}
+
+		output, ok, err := c.outputPath(template, namer, app.Playback.Info(), outputContext)
+		if err != nil {
+			failClip(i, err)
+			continue
+		}
+		if !ok {
+			fmt.Println("  Output already exists, skipping")
+			continue
+		}
+
+		state := c.newState(app, filter, interval, outputContext, output)
+		state.Chapters = chapters
+		states = append(states, state)
+	}
+
+	d := &downloader{app: app}
+
+	for i, state := range states {
+		fmt.Printf("(<<) Downloading clip %d of %d...\n", i+1, len(states))
+		if err := d.download(state, statePath(state.Output)); err != nil {
+			slog.Error("failed to download clip", "output", state.Output, "err", err)
+			errs = append(errs, fmt.Errorf("clip %s: %w", state.Output, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf(
+			"%d of %d clips failed: %w",
+			len(errs),
+			len(clips),
+			errors.Join(errs...),
+		)
+	}
+
+	return nil
+}
+
+// locateChapters locates chapter marks within the interval.
+func locateChapters(
+	app *apppkg.App,
+	interval *playback.RewindInterval,
+	marks []actions.ChapterMark,
+	locateContext *actions.LocateContext,
+) ([]actions.Chapter, error) {
+	if len(marks) == 0 {
+		return nil, nil
+	}
+
+	chapters, err := actions.LocateChapters(app.Playback, interval, marks, locateContext)
+	if err != nil {
+		return nil, fmt.Errorf("locating chapters: %w", err)
+	}
+	fmt.Printf("Located %d of %d chapters\n", len(chapters), len(marks))
+
+	return chapters, nil
+}
+
+// newApp creates an app with the collected video info, restricted to the
+// streams to download.
+func (c *Download) newApp() (*apppkg.App, info.StreamFilter, error) {
+	app := apppkg.NewApp()
+
+	if err := CollectVideoInfo(c.Stream, app, c.config(), c.tz); err != nil {
+		return nil, info.StreamFilter{}, err
+	}
+	filter, err := c.streamFilter(app.Playback.Info())
+	if err != nil {
+		return nil, info.StreamFilter{}, err
+	}
+	if err := FilterStreams(app, filter); err != nil {
+		return nil, info.StreamFilter{}, err
+	}
+
+	return app, filter, nil
+}
+
+// outputPath returns the path of the output of the located interval, or
+// false if the output is skipped.
+func (c *Download) outputPath(
+	template *OutputTemplate,
+	namer *OutputNamer,
+	videoInfo info.VideoInformation,
+	ctx *actions.LocateOutputContext,
+) (string, bool, error) {
+	ext := ytdlpExtPlaceholder
+	if c.Native || c.selectsTracks() {
+		ext = outputExtension(videoInfo)
+	}
+
+	return namer.Resolve(template.Execute(OutputFields{
+		Title:    ctx.Title,
+		ID:       ctx.ID,
+		Channel:  videoInfo.ChannelTitle,
+		Start:    ctx.InputStartTime,
+		End:      ctx.InputEndTime,
+		Duration: ctx.InputDuration,
+		Sq:       ctx.StartSequenceNumber,
+		Ext:      ext,
+	}))
+}
+
+// newState creates the state of a download of the located interval.
+func (c *Download) newState(
+	app *apppkg.App,
+	filter info.StreamFilter,
+	interval *playback.RewindInterval,
+	outputContext *actions.LocateOutputContext,
+	output string,
+) *downloadState {
+	return &downloadState{
+		Stream:        c.Stream,
+		Fetcher:       c.Fetcher,
+		FetcherSource: c.FetcherSource,
+		Output:        output,
+		Overwrite:     c.OnCollision == CollisionOverwrite,
+		Native:        c.Native,
+		YtdlpOptions:  c.YtdlpOptions,
+		Filter:        filter,
+		Start:         newStateMoment(interval.Start),
+		End:           newStateMoment(interval.End),
+		Provenance: actions.NewProvenance(
+			app.Playback.Info(),
+			downloadItags(app.Playback.Info()),
+		).ForInterval(outputContext),
+		EmbedMetadata: c.EmbedMetadata,
+		Sidecar:       c.Sidecar,
+	}
+}
+
+// resume continues an interrupted download from its state file.
+func (c *Download) resume() error {
+	state, err := loadDownloadState(c.Resume)
+	if err != nil {
+		return err
+	}
+
+	// Stream info is collected with the fetcher the download was started with
+	flags := c.CommonFlags
+	if state.Fetcher != "" {
+		flags.Fetcher, flags.FetcherSource = state.Fetcher, state.FetcherSource
+	}
+	if err := flags.CheckFetcher(c.tz); err != nil {
+		return err
+	}
+	cfg := flags.Config()
+	cfg.Prefetch = c.Prefetch
+
+	app := apppkg.NewApp()
+	if err := CollectVideoInfo(state.Stream, app, cfg, c.tz); err != nil {
+		return err
+	}
+	if err := FilterStreams(app, state.Filter); err != nil {
+		return err
+	}
+
+	interval := state.Interval()
+	fmt.Println("(<<) Resuming download of the interval:")
+	fmt.Println(formatActualLine(c.tz, "start", interval.Start))
+	fmt.Println(" ", formatActualLine(c.tz, "end", interval.End))
+
+	return (&downloader{app: app}).download(state, c.Resume)
+}
+
+// downloadItags returns itags of the best video and audio streams, which are
+// downloaded by default.
+func downloadItags(videoInfo info.VideoInformation) []string {
+	var itags []string
+	if video := videoInfo.BestVideo(); video != nil {
+		itags = append(itags, video.Itag)
+	}
+	if audio := videoInfo.BestAudio(); audio != nil {
+		itags = append(itags, audio.Itag)
+	}
+	return itags
+}
+
+// selectsTracks reports whether tracks to download are chosen with flags.
+func (c *Download) selectsTracks() bool {
+	return c.AudioOnly || c.VideoOnly || c.Format != ""
+}
+
+// streamFilter returns the filter of streams to download, combining filter
+// flags with track selection ones.
+func (c *Download) streamFilter(videoInfo info.VideoInformation) (info.StreamFilter, error) {
+	filter := c.Filter()
+
+	switch {
+	case c.AudioOnly:
+		filter.Video, filter.Audio = info.SelectNone, info.SelectBest
+	case c.VideoOnly:
+		filter.Video, filter.Audio = info.SelectBest, info.SelectNone
+	case c.Format != "":
+		itags := strings.Split(c.Format, "+")
+		for _, itag := range itags {
+			if !hasItag(videoInfo, itag) {
+				return filter, fmt.Errorf("unknown itag in format: %q", itag)
+			}
+		}
+		filter.Itags = itags
+	}
+
+	return filter, nil
+}
+
+func hasItag(videoInfo info.VideoInformation, itag string) bool {
+	for _, s := range videoInfo.VideoStreams {
+		if s.Itag == itag {
+			return true
+		}
+	}
+	for _, s := range videoInfo.AudioStreams {
+		if s.Itag == itag {
+			return true
+		}
+	}
+	return false
+}
+
+// outputExtension returns the extension of the output containing the streams:
+// m4a (or webm) for audio only, mp4 (or webm) otherwise.
+func outputExtension(videoInfo info.VideoInformation) string {
+	isWebM := func(mimeType string) bool {
+		return strings.Contains(mimeType, "webm")
+	}
+
+	if len(videoInfo.VideoStreams) == 0 {
+		if audio := videoInfo.BestAudio(); audio != nil && isWebM(audio.MimeType) {
+			return "webm"
+		}
+		return "m4a"
+	}
+
+	if isWebM(videoInfo.BestVideo().MimeType) {
+		return "webm"
+	}
+	return "mp4"
+}
+
+func (c *Download) config() *apppkg.Config {
+	cfg := c.CommonFlags.Config()
+	cfg.Prefetch = c.Prefetch
+	return cfg
+}
+
+// downloader downloads intervals of a stream one after another. With yt-dlp,
+// the manifest server is started once and reused for all intervals.
+type downloader struct {
+	app *apppkg.App
+
+	// state is the state of the download served to yt-dlp.
+	state     atomic.Pointer[downloadState]
+	serveOnce sync.Once
+}
+
+// download downloads media of the state's interval, persisting the state until
+// it's done. Native downloads also track downloaded segments in the state,
+// while yt-dlp continues its own partially downloaded files.
+func (d *downloader) download(state *downloadState, statePath string) error {
+	if err := state.save(statePath); err != nil {
+		return fmt.Errorf("saving download state: %w", err)
+	}
+
+	var err error
+	if state.Native {
+		err = d.downloadNative(state, statePath)
+	} else {
+		err = d.downloadWithYtdlp(state)
+	}
+	if err != nil {
+		return fmt.Errorf("%w (resume with --resume %s)", err, statePath)
+	}
+
+	if err := os.Remove(statePath); err != nil {
+		slog.Warn("failed to remove download state", "path", statePath, "err", err)
+	}
+
+	return nil
+}
+
+func (d *downloader) downloadWithYtdlp(state *downloadState) error {
+	// Downloading with yt-dlp needs it regardless of the fetcher
+	if err := checkYtdlp(); err != nil {
+		return err
+	}
+
+	app := d.app
+	d.state.Store(state)
+	d.serveOnce.Do(d.startServer)
 
 	mpdURL, err := url.JoinPath(urlutil.FormatServerAddress(app.Server.Addr), "mpd")
 	if err != nil {
 		return fmt.Errorf("building URL: %w", err)
 	}
 
-	ytdlpOptions := c.YtdlpOptions
+	ytdlpOptions := state.YtdlpOptions
 	if len(ytdlpOptions) > 0 && ytdlpOptions[0] == "--" {
 		ytdlpOptions = ytdlpOptions[1:]
 	}
-	args := append(
-		[]string{
-			mpdURL,
-			"--force-generic-extractor",
-			"--output", buildOutputName(outputContext),
-		},
-		ytdlpOptions...,
-	)
+	args := []string{
+		mpdURL,
+		"--force-generic-extractor",
+		"--output", state.Output,
+	}
+	if state.Overwrite {
+		args = append(args, "--force-overwrites")
+	}
+	args = append(args, ytdlpOptions...)
 
 	fmt.Println("(<<) Downloading and merging media...")
 	if err := app.YtdlpRunner.Run(context.Background(), args...); err != nil {
 		return fmt.Errorf("downloading failed: %w", err)
 	}
 
+	if !state.metadata().IsEmpty() || state.Sidecar {
+		if err := writeYtdlpMetadata(app, state); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func serveMPD(w http.ResponseWriter, app *apppkg.App, interval *playback.RewindInterval) error {
+// writeYtdlpMetadata writes metadata to the output of yt-dlp, found by
+// expanding the extension of its output template.
+func writeYtdlpMetadata(app *apppkg.App, state *downloadState) error {
+	matches := findYtdlpOutputs(state.Output)
+	if len(matches) != 1 {
+		slog.Warn("output file not found, metadata is not written", "output", state.Output)
+		return nil
+	}
+	output := matches[0]
+
+	if metadata := state.metadata(); !metadata.IsEmpty() {
+		fmt.Println("(<<) Writing metadata...")
+		if err := actions.WriteMetadata(output, metadata, app.FFmpegRunner); err != nil {
+			return fmt.Errorf("writing metadata: %w", err)
+		}
+	}
+
+	return writeSidecar(state, output)
+}
+
+// findYtdlpOutputs returns files matching the yt-dlp output template, by
+// expanding its extension placeholder. Files of ypb itself and partial
+// downloads are excluded.
+func findYtdlpOutputs(output string) []string {
+	matches, err := filepath.Glob(strings.ReplaceAll(output, ytdlpExtPlaceholder, "*"))
+	if err != nil {
+		slog.Debug("bad output template pattern", "output", output, "err", err)
+		return nil
+	}
+	return slices.DeleteFunc(matches, func(path string) bool {
+		return strings.HasSuffix(path, stateFileSuffix) ||
+			strings.HasSuffix(path, ".part") ||
+			path == SidecarPath(output)
+	})
+}
+
+// writeSidecar writes provenance of the output to its sidecar file, if
+// requested.
+func writeSidecar(state *downloadState, output string) error {
+	if !state.Sidecar || state.Provenance == nil {
+		return nil
+	}
+	if err := state.Provenance.WriteSidecar(SidecarPath(output)); err != nil {
+		return fmt.Errorf("writing provenance: %w", err)
+	}
+	return nil
+}
+
+// Progress of native downloads is saved to the state every stateSaveSegments
+// segments or stateSaveInterval, whichever comes first.
+const (
+	stateSaveSegments = 50
+	stateSaveInterval = 5 * time.Second
+)
+
+// downloadNative downloads the best video and audio tracks, among the
+// filtered ones, and muxes them into the output file. Progress of tracks is
+// saved to the state periodically while downloading, and on failure.
+func (d *downloader) downloadNative(state *downloadState, statePath string) error {
+	app := d.app
+	if len(state.Tracks) == 0 {
+		itags := downloadItags(app.Playback.Info())
+		if len(itags) == 0 {
+			return errors.New("no video or audio streams available")
+		}
+		for _, itag := range itags {
+			state.Tracks = append(state.Tracks, actions.Track{
+				Itag: itag,
+				Path: fmt.Sprintf("%s.%s.part", state.Output, itag),
+			})
+		}
+	}
+
+	saveState := func() {
+		if err := state.save(statePath); err != nil {
+			slog.Warn("failed to save download state", "err", err)
+		}
+	}
+
+	fmt.Println("(<<) Downloading media...")
+	lastSaved, lastSaveTime := 0, time.Now()
+	err := actions.DownloadTracks(
+		app.Playback,
+		state.Interval(),
+		state.Tracks,
+		func(done, total int) {
+			if done-lastSaved >= stateSaveSegments ||
+				time.Since(lastSaveTime) >= stateSaveInterval {
+				saveState()
+				lastSaved, lastSaveTime = done, time.Now()
+			}
+			fmt.Printf("\rDownloaded %d of %d segments", done, total)
+			if done == total {
+				fmt.Println()
+			}
+		},
+	)
+	if err != nil {
+		// Segments downloaded since the last save are kept for resuming
+		saveState()
+		fmt.Println()
+		return fmt.Errorf("downloading failed: %w", err)
+	}
+
+	fmt.Println("(<<) Merging media...")
+	err = actions.MuxTracks(state.Tracks, state.metadata(), state.Output, app.FFmpegRunner)
+	if err != nil {
+		return fmt.Errorf("merging failed: %w", err)
+	}
+
+	for _, track := range state.Tracks {
+		if err := os.Remove(track.Path); err != nil {
+			slog.Warn("failed to remove track file", "path", track.Path, "err", err)
+		}
+	}
+
+	if err := writeSidecar(state, state.Output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved to %s\n", state.Output)
+
+	return nil
+}
+
+// startServer starts the server of manifests and segments for yt-dlp.
+func (d *downloader) startServer() {
+	app := d.app
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mpd", apppkg.WithError(
+		func(w http.ResponseWriter, r *http.Request) error {
+			state := d.state.Load()
+			return serveMPD(w, app, state.Interval(), state.Chapters)
+		}),
+	)
+	segmentHandler := &apppkg.SegmentHandler{
+		Playback: app.Playback,
+	}
+	mux.HandleFunc(apppkg.SegmentPath, apppkg.WithError(segmentHandler.ServeHTTP))
+	mux.HandleFunc(apppkg.TimePath, apppkg.WithError((&apppkg.TimeHandler{}).ServeHTTP))
+
+	app.Server.Handler = mux
+
+	go func() {
+		slog.Debug("starting server", "addr", app.Server.Addr)
+		err := app.Server.ListenAndServe()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}()
+}
+
+func serveMPD(
+	w http.ResponseWriter,
+	app *apppkg.App,
+	interval *playback.RewindInterval,
+	chapters []actions.Chapter,
+) error {
 	out, err := actions.ComposeStatic(
 		app.Playback,
 		interval,
+		chapters,
 		urlutil.FormatServerAddress(app.Server.Addr),
 		app.FFprobeRunner,
 	)
@@ -129,7 +722,9 @@ func serveMPD(w http.ResponseWriter, app *apppkg.App, interval *playback.RewindI
 	return nil
 }
 
-func formatActualLine(side string, moment *playback.RewindMoment) string {
+// formatActualLine formats the actual time of the moment, shown in the time
+// zone.
+func formatActualLine(tz *Timezone, side string, moment *playback.RewindMoment) string {
 	diffPart := ""
 
 	diff := moment.TimeDifference()
@@ -140,18 +735,8 @@ func formatActualLine(side string, moment *playback.RewindMoment) string {
 	return fmt.Sprintf(
 		"Actual %s: %s%s, sq=%d",
 		side,
-		moment.ActualTime.Format(time.RFC1123Z),
+		tz.In(moment.ActualTime).Format(time.RFC1123Z),
 		diffPart,
 		moment.Metadata.SequenceNumber,
 	)
 }
-
-func buildOutputName(ctx *actions.LocateOutputContext) string {
-	return fmt.Sprintf(
-		"%s_%s_%s_%s.%%(ext)s",
-		AdjustForFilename(ctx.Title, 0),
-		ctx.ID,
-		FormatTime(ctx.InputStartTime),
-		FormatDuration(ctx.InputDuration),
-	)
-}
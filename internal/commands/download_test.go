@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/testutil"
 )
 
 //nolint:paralleltest
@@ -174,3 +179,78 @@ func TestFormatDifference(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputExtension(t *testing.T) {
+	t.Parallel()
+
+	mp4Audio := info.AudioStream{CommonStream: info.CommonStream{Itag: "140", MimeType: "audio/mp4"}}
+	webmAudio := info.AudioStream{CommonStream: info.CommonStream{Itag: "251", MimeType: "audio/webm"}}
+	mp4Video := info.VideoStream{CommonStream: info.CommonStream{Itag: "137", MimeType: "video/mp4"}}
+	webmVideo := info.VideoStream{CommonStream: info.CommonStream{Itag: "248", MimeType: "video/webm"}}
+
+	testCases := []struct {
+		name     string
+		info     info.VideoInformation
+		expected string
+	}{
+		{
+			name: "video and audio",
+			info: info.VideoInformation{
+				VideoStreams: []info.VideoStream{mp4Video},
+				AudioStreams: []info.AudioStream{mp4Audio},
+			},
+			expected: "mp4",
+		},
+		{
+			name:     "video only",
+			info:     info.VideoInformation{VideoStreams: []info.VideoStream{mp4Video}},
+			expected: "mp4",
+		},
+		{
+			name:     "webm video only",
+			info:     info.VideoInformation{VideoStreams: []info.VideoStream{webmVideo}},
+			expected: "webm",
+		},
+		{
+			name:     "audio only",
+			info:     info.VideoInformation{AudioStreams: []info.AudioStream{mp4Audio}},
+			expected: "m4a",
+		},
+		{
+			name:     "webm audio only",
+			info:     info.VideoInformation{AudioStreams: []info.AudioStream{webmAudio}},
+			expected: "webm",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, outputExtension(tc.info))
+		})
+	}
+}
+
+func TestDownloadStreamFilter(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &testutil.MockFetcher{VideoID: testutil.TestVideoID}
+	videoInfo, _, err := fetcher.FetchInfo(context.Background())
+	require.NoError(t, err)
+
+	filter, err := (&Download{AudioOnly: true}).streamFilter(*videoInfo)
+	require.NoError(t, err)
+	assert.Equal(t, info.SelectNone, filter.Video)
+	assert.Equal(t, info.SelectBest, filter.Audio)
+
+	filter, err = (&Download{VideoOnly: true}).streamFilter(*videoInfo)
+	require.NoError(t, err)
+	assert.Equal(t, info.SelectBest, filter.Video)
+	assert.Equal(t, info.SelectNone, filter.Audio)
+
+	filter, err = (&Download{Format: "137+140"}).streamFilter(*videoInfo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"137", "140"}, filter.Itags)
+
+	_, err = (&Download{Format: "137+999"}).streamFilter(*videoInfo)
+	require.ErrorContains(t, err, "unknown itag")
+}
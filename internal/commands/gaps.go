@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	apppkg "github.com/xymaxim/ypb/internal/app"
+)
+
+type Gaps struct {
+	CommonFlags
+	Stream   string `arg:"" help:"YouTube video ID"         required:""`
+	Interval string `       help:"Time or segment interval" required:"" short:"i"`
+	JSON     bool   `       help:"Print the report as JSON"`
+}
+
+func (c *Gaps) Run(tz *Timezone) error {
+	pinnedTime := time.Now().UTC()
+
+	if err := c.CheckFetcher(tz); err != nil {
+		return err
+	}
+
+	app := apppkg.NewApp()
+
+	start, end, err := ParseInterval(c.Interval, tz)
+	if err != nil {
+		return err
+	}
+
+	if err := CollectVideoInfo(c.Stream, app, c.CommonFlags.Config(), tz); err != nil {
+		return err
+	}
+	if tz.FollowsStream() {
+		// Times are read again once the stream's time zone is known
+		if start, end, err = ParseInterval(c.Interval, tz); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("(<<) Locating start and end moments...")
+	locateContext, err := actions.NewLocateContext(app.Playback, nil, &pinnedTime)
+	if err != nil {
+		return fmt.Errorf("building locate context: %w", err)
+	}
+
+	interval, _, err := actions.LocateInterval(app.Playback, start, end, locateContext)
+	if err != nil {
+		return fmt.Errorf("locating interval: %w", err)
+	}
+
+	fmt.Printf(
+		"(<<) Scanning %d segments for gaps...\n",
+		interval.End.Metadata.SequenceNumber-interval.Start.Metadata.SequenceNumber+1,
+	)
+	report, err := apppkg.BuildGapReport(app.Playback, interval)
+	if err != nil {
+		return err
+	}
+
+	if c.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("writing json report: %w", err)
+		}
+		return nil
+	}
+
+	if len(report.Gaps) == 0 {
+		fmt.Println("No gaps found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "START SQ\tEND SQ\tSTART TIME\tEND TIME\tDURATION")
+	for _, g := range report.Gaps {
+		fmt.Fprintf(
+			w,
+			"%d\t%d\t%s\t%s\t%s\n",
+			g.StartSequenceNumber,
+			g.EndSequenceNumber,
+			tz.In(g.StartTime).Format(time.RFC3339Nano),
+			tz.In(g.EndTime).Format(time.RFC3339Nano),
+			time.Duration(g.Duration*float64(time.Second)).Round(time.Millisecond),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	apppkg "github.com/xymaxim/ypb/internal/app"
+	"github.com/xymaxim/ypb/internal/input"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+// recordPollInterval is how often the head segment is requested when waiting
+// for new segments.
+const recordPollInterval = 2 * time.Second
+
+// recordTemplateFields are fields of output templates of recordings.
+var recordTemplateFields = []string{
+	FieldTitle, FieldID, FieldChannel, FieldStart, FieldSq, FieldExt,
+}
+
+type Record struct {
+	CommonFlags
+	FilterFlags
+	OutputFlags
+	Stream     string        `arg:"" help:"YouTube video ID"                                 required:""`
+	Start      string        `       help:"Moment to start recording from"                   default:"now" short:"s"`
+	Duration   time.Duration `       help:"Stop after recording the duration"`
+	Until      string        `       help:"Stop at the date and time"`
+	SplitEvery time.Duration `       help:"Start a new file after the duration"`
+	SplitSize  ByteSize      `       help:"Start a new file after the size, in bytes or with a K, M, or G suffix" placeholder:"SIZE"`
+}
+
+func (c *Record) Run(tz *Timezone) error {
+	if err := c.CheckFetcher(tz); err != nil {
+		return err
+	}
+
+	start, until, err := c.parseMoments(tz)
+	if err != nil {
+		return err
+	}
+
+	template, err := c.Template(tz, DefaultRecordTemplate, recordTemplateFields...)
+	if err != nil {
+		return err
+	}
+	namer := c.Namer()
+
+	app := apppkg.NewApp()
+	if err := CollectVideoInfo(c.Stream, app, c.CommonFlags.Config(), tz); err != nil {
+		return err
+	}
+	if tz.FollowsStream() {
+		// Times are read again once the stream's time zone is known
+		if start, until, err = c.parseMoments(tz); err != nil {
+			return err
+		}
+	}
+
+	if err := FilterStreams(app, c.Filter()); err != nil {
+		return err
+	}
+	itags := downloadItags(app.Playback.Info())
+	if len(itags) == 0 {
+		return errors.New("no video or audio streams available")
+	}
+
+	locateContext, err := actions.NewLocateContext(app.Playback, nil, nil)
+	if err != nil {
+		return fmt.Errorf("building locate context: %w", err)
+	}
+	startMoment, err := actions.LocateMoment(app.Playback, start, locateContext)
+	if err != nil {
+		return fmt.Errorf("locating start moment: %w", err)
+	}
+	fmt.Println(formatActualLine(tz, "start", startMoment))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Println("(<<) Recording, press Ctrl+C to stop...")
+	stopAt := startMoment.Metadata.Time().Add(c.Duration)
+	err = actions.Record(ctx, app.Playback, actions.RecordOptions{
+		Itags:        itags,
+		Start:        startMoment.Metadata.SequenceNumber,
+		PollInterval: recordPollInterval,
+		ShouldStop: func(m *segment.Metadata) bool {
+			if c.Duration > 0 && !m.Time().Before(stopAt) {
+				return true
+			}
+			return !until.IsZero() && !m.Time().Before(until)
+		},
+		ShouldSplit: func(chunk *actions.RecordChunk) bool {
+			if c.SplitEvery > 0 && chunk.Duration() >= c.SplitEvery {
+				return true
+			}
+			return c.SplitSize > 0 && chunk.Size() >= int64(c.SplitSize)
+		},
+		ChunkOutput: func(chunk *actions.RecordChunk) (string, error) {
+			return c.outputPath(template, namer, app, chunk)
+		},
+		ChunkPath: func(chunk *actions.RecordChunk, itag string) string {
+			return fmt.Sprintf("%s.%s.part", chunk.Output, itag)
+		},
+		OnSegment: func(chunk *actions.RecordChunk, m *segment.Metadata) {
+			fmt.Printf(
+				"\rRecorded %s to %s, sq=%d",
+				FormatDuration(chunk.Duration()),
+				chunk.Output,
+				m.SequenceNumber,
+			)
+		},
+		OnChunk: func(chunk *actions.RecordChunk) error {
+			fmt.Println()
+			err := actions.MuxTracks(chunk.Tracks, nil, chunk.Output, app.FFmpegRunner)
+			if err != nil {
+				return err
+			}
+			for _, track := range chunk.Tracks {
+				if err := os.Remove(track.Path); err != nil {
+					slog.Warn(
+						"failed to remove track file",
+						"path", track.Path,
+						"err", err,
+					)
+				}
+			}
+			fmt.Printf("Saved to %s\n", chunk.Output)
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("recording failed: %w", err)
+	}
+
+	return nil
+}
+
+// parseMoments parses the start moment and the end time, if given.
+func (c *Record) parseMoments(tz *Timezone) (input.MomentValue, time.Time, error) {
+	start, err := input.ParseIntervalPartIn(c.Start, tz.Location())
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parsing start moment: %w", err)
+	}
+
+	var until time.Time
+	if c.Until != "" {
+		value, err := input.ParseIntervalPartIn(c.Until, tz.Location())
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("parsing end time: %w", err)
+		}
+		var ok bool
+		if until, ok = value.(time.Time); !ok {
+			return nil, time.Time{}, errors.New("end time should be a date and time")
+		}
+	}
+
+	return start, until, nil
+}
+
+// outputPath returns the path to save the chunk to. Existing outputs cannot
+// be skipped, since the stream is recorded as it goes.
+func (c *Record) outputPath(
+	template *OutputTemplate,
+	namer *OutputNamer,
+	app *apppkg.App,
+	chunk *actions.RecordChunk,
+) (string, error) {
+	videoInfo := app.Playback.Info()
+	output, ok, err := namer.Resolve(template.Execute(OutputFields{
+		Title:   videoInfo.Title,
+		ID:      videoInfo.ID,
+		Channel: videoInfo.ChannelTitle,
+		Start:   chunk.First.Time(),
+		Sq:      chunk.First.SequenceNumber,
+		Ext:     outputExtension(videoInfo),
+	}))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("output already exists")
+	}
+	return output, nil
+}
@@ -1,8 +1,8 @@
 package commands
 
 import (
+	"context"
 	"fmt"
-	"net/http"
 
 	apppkg "github.com/xymaxim/ypb/internal/app"
 	"github.com/xymaxim/ypb/internal/urlutil"
@@ -10,41 +10,56 @@ import (
 
 type Serve struct {
 	CommonFlags
-	Stream string `arg:"" help:"YouTube video ID" required:""`
+	PrefetchFlags
+	Streams     []string `arg:"" help:"YouTube video IDs"                                       optional:""`
+	StreamClock bool     `       help:"Synchronize players with the stream clock instead of the local one"`
+	APIToken    string   `       help:"Token required by the stream management API, which otherwise only accepts local requests" env:"YPB_API_TOKEN"` //nolint:lll
 }
 
-func (c *Serve) Run() error {
-	if err := checkYtdlp(); err != nil {
+func (c *Serve) Run(tz *Timezone) error {
+	if err := c.CheckFetcher(tz); err != nil {
 		return err
 	}
+	for _, id := range c.Streams {
+		if !urlutil.IsVideoID(id) {
+			return fmt.Errorf("bad video id: %q", id)
+		}
+	}
 
 	app := apppkg.NewApp()
 
-	if err := CollectVideoInfo(c.Stream, app, c.Port); err != nil {
-		return err
+	cfg := c.CommonFlags.Config()
+	cfg.Prefetch = c.Prefetch
+	cfg.StreamClock = c.StreamClock
+	cfg.APIToken = c.APIToken
+	cfg.Location = tz.Location()
+	cfg.StreamLocation = tz.FollowsStream()
+	if err := app.Configure(cfg); err != nil {
+		return fmt.Errorf("configuring app: %w", err)
+	}
+
+	// Streams are started up front, so that failing ones stop the server
+	registry := apppkg.NewRegistry(context.Background(), app.NewPlayback)
+	for _, id := range c.Streams {
+		registry.Add(id)
+		fmt.Printf("(<<) Collecting info about %s...\n", urlutil.BuildVideoLiveURL(id))
+		pb, err := registry.Playback(id)
+		if err != nil {
+			return fmt.Errorf("starting stream %s: %w", id, err)
+		}
+		fmt.Printf("Stream '%s' is alive!\n", pb.Info().Title)
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc(apppkg.InfoPath, apppkg.WithError(
-		(&apppkg.InfoHandler{Info: app.Playback.Info()}).ServeHTTP),
-	)
-	mux.HandleFunc(apppkg.MPDPath, apppkg.WithError(
-		(&apppkg.MPDHandler{
-			Playback:      app.Playback,
-			FFprobeRunner: app.FFprobeRunner,
-			ServerAddr:    app.Server.Addr,
-		}).ServeHTTP),
-	)
-	mux.HandleFunc(apppkg.SegmentPath, apppkg.WithError(
-		(&apppkg.SegmentHandler{Playback: app.Playback}).ServeHTTP),
-	)
-
-	app.Server.Handler = mux
-
-	fmt.Printf(
-		"(<<) Playback started and listening on %s...\n",
-		urlutil.FormatServerAddress(app.Server.Addr),
-	)
+	app.Server.Handler = apppkg.NewMultiStreamMux(app, registry)
+	if len(c.Streams) == 1 {
+		app.Server.Handler = registry.WithDefaultStream(c.Streams[0], app.Server.Handler)
+	}
+
+	serverURL := urlutil.FormatServerAddress(app.Server.Addr)
+	fmt.Printf("(<<) Playback started and listening on %s...\n", serverURL)
+	for _, id := range registry.IDs() {
+		fmt.Printf("  %s/%s/\n", serverURL, id)
+	}
 
 	return app.Server.ListenAndServe()
 }
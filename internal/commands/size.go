@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are multipliers of size suffixes, in powers of 1024.
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// ByteSize is a size in bytes, given as a number with an optional K, M, or G
+// suffix (e.g., 500M), with an optional trailing 'B' or 'iB'.
+type ByteSize int64
+
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	value := strings.ToUpper(strings.TrimSpace(string(text)))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = number, unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("bad size: %q", text)
+	}
+	*s = ByteSize(n * multiplier)
+
+	return nil
+}
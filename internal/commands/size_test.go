@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteSize_UnmarshalText(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input    string
+		expected ByteSize
+	}{
+		{"1000", 1000},
+		{"1000B", 1000},
+		{"2K", 2 * 1024},
+		{"500M", 500 * 1024 * 1024},
+		{"500MiB", 500 * 1024 * 1024},
+		{"1gb", 1024 * 1024 * 1024},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			var actual ByteSize
+			require.NoError(t, actual.UnmarshalText([]byte(tc.input)))
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	for _, input := range []string{"", "M", "-1M", "1.5G", "1T"} {
+		var actual ByteSize
+		assert.Error(t, actual.UnmarshalText([]byte(input)), input)
+	}
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+const stateFileSuffix = ".ypb-state.json"
+
+// downloadState is persisted next to the output to resume an interrupted
+// download without locating the interval again. Downloads are resumed in the
+// same mode, with yt-dlp options, if any.
+type downloadState struct {
+	Stream string `json:"stream"`
+	// Fetcher and FetcherSource are of the fetcher of stream info, used again
+	// on resuming. If empty, the fetcher flags are used.
+	Fetcher       string            `json:"fetcher,omitempty"`
+	FetcherSource []string          `json:"fetcherSource,omitempty"`
+	Output        string            `json:"output"`
+	Overwrite     bool              `json:"overwrite,omitempty"`
+	Native        bool              `json:"native"`
+	YtdlpOptions  []string          `json:"ytdlpOptions,omitempty"`
+	Filter        info.StreamFilter `json:"filter"`
+	Start         stateMoment       `json:"start"`
+	End           stateMoment       `json:"end"`
+	Chapters      []actions.Chapter `json:"chapters,omitempty"`
+	// Provenance of the output, embedded into it if EmbedMetadata is set and
+	// written to a sidecar file if Sidecar is.
+	Provenance    *actions.Provenance `json:"provenance,omitempty"`
+	EmbedMetadata bool                `json:"embedMetadata,omitempty"`
+	Sidecar       bool                `json:"sidecar,omitempty"`
+	Tracks        []actions.Track     `json:"tracks,omitempty"`
+}
+
+type stateMoment struct {
+	SequenceNumber      int       `json:"sq"`
+	IngestionWalltimeUs int64     `json:"ingestionWalltimeUs"`
+	DurationUs          int64     `json:"durationUs"`
+	TargetTime          time.Time `json:"targetTime"`
+}
+
+func newStateMoment(m *playback.RewindMoment) stateMoment {
+	return stateMoment{
+		SequenceNumber:      m.Metadata.SequenceNumber,
+		IngestionWalltimeUs: m.Metadata.IngestionWalltime.UnixMicro(),
+		DurationUs:          m.Metadata.Duration.Microseconds(),
+		TargetTime:          m.TargetTime,
+	}
+}
+
+func (m stateMoment) rewindMoment(isEnd bool) *playback.RewindMoment {
+	metadata := segment.Metadata{
+		SequenceNumber:    m.SequenceNumber,
+		IngestionWalltime: time.UnixMicro(m.IngestionWalltimeUs).UTC(),
+		Duration:          time.Duration(m.DurationUs) * time.Microsecond,
+	}
+	return playback.NewRewindMoment(m.TargetTime, metadata, isEnd, false)
+}
+
+// Interval returns the located interval.
+func (s *downloadState) Interval() *playback.RewindInterval {
+	return &playback.RewindInterval{
+		Start: s.Start.rewindMoment(false),
+		End:   s.End.rewindMoment(true),
+	}
+}
+
+// metadata returns the metadata to write to the output.
+func (s *downloadState) metadata() *actions.MediaMetadata {
+	metadata := &actions.MediaMetadata{
+		Chapters: s.Chapters,
+		Duration: s.Interval().Duration(),
+	}
+	if s.EmbedMetadata && s.Provenance != nil {
+		metadata.Tags = s.Provenance.Tags()
+	}
+	return metadata
+}
+
+// statePath returns the path of a state file for an output path or template.
+func statePath(output string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + stateFileSuffix
+}
+
+func loadDownloadState(path string) (*downloadState, error) {
+	b, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+
+	var state downloadState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("parsing state file: %w", err)
+	}
+
+	if err := state.validate(); err != nil {
+		return nil, fmt.Errorf("bad state file: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (s *downloadState) validate() error {
+	switch {
+	case s.Stream == "":
+		return errors.New("missing stream")
+	case s.Output == "":
+		return errors.New("missing output")
+	case s.Start.IngestionWalltimeUs == 0 || s.End.IngestionWalltimeUs == 0:
+		return errors.New("missing interval")
+	case s.End.SequenceNumber < s.Start.SequenceNumber:
+		return fmt.Errorf(
+			"end segment is before start segment: %d < %d",
+			s.End.SequenceNumber,
+			s.Start.SequenceNumber,
+		)
+	}
+	return nil
+}
+
+// save writes the state atomically, so an interruption never leaves a broken
+// state file.
+func (s *downloadState) save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming state file: %w", err)
+	}
+
+	return nil
+}
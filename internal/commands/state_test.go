@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/actions"
+	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+func TestStatePath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "out/a_1h.ypb-state.json", statePath("out/a_1h.%(ext)s"))
+	assert.Equal(t, "a_1h.ypb-state.json", statePath("a_1h.mp4"))
+}
+
+func TestDownloadState_SaveAndLoad(t *testing.T) {
+	t.Parallel()
+
+	walltime := time.Date(2026, 1, 2, 10, 20, 30, 123456000, time.UTC)
+	start := playback.NewRewindMoment(
+		walltime.Add(-time.Second),
+		segment.Metadata{SequenceNumber: 100, IngestionWalltime: walltime, Duration: 2 * time.Second},
+		false,
+		false,
+	)
+	end := playback.NewRewindMoment(
+		walltime.Add(time.Minute),
+		segment.Metadata{
+			SequenceNumber:    130,
+			IngestionWalltime: walltime.Add(time.Minute),
+			Duration:          2 * time.Second,
+		},
+		true,
+		false,
+	)
+
+	state := &downloadState{
+		Stream:        "abcdefgh123",
+		Fetcher:       "file",
+		FetcherSource: []string{"info.json"},
+		Output:        "output.mp4",
+		Native:        true,
+		Start:         newStateMoment(start),
+		End:           newStateMoment(end),
+		Tracks: []actions.Track{
+			{Itag: "137", Path: "output.mp4.137.part", Completed: 5, Size: 1000},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "output.ypb-state.json")
+	require.NoError(t, state.save(path))
+
+	loaded, err := loadDownloadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, state, loaded)
+	assert.Equal(t, &playback.RewindInterval{Start: start, End: end}, loaded.Interval())
+}
+
+func TestLoadDownloadState_Invalid(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "output.ypb-state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"stream": "abcdefgh123"}`), 0o600))
+
+	_, err := loadDownloadState(path)
+	assert.Error(t, err)
+}
+
+func TestLoadDownloadState_Ytdlp(t *testing.T) {
+	t.Parallel()
+
+	walltime := time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)
+	moment := playback.NewRewindMoment(
+		walltime,
+		segment.Metadata{
+			SequenceNumber:    100,
+			IngestionWalltime: walltime,
+			Duration:          2 * time.Second,
+		},
+		false,
+		false,
+	)
+	state := &downloadState{
+		Stream:       "abcdefgh123",
+		Output:       "output.%(ext)s",
+		YtdlpOptions: []string{"--", "--limit-rate", "1M"},
+		Start:        newStateMoment(moment),
+		End:          newStateMoment(moment),
+	}
+
+	path := filepath.Join(t.TempDir(), statePath(state.Output))
+	require.NoError(t, state.save(path))
+
+	loaded, err := loadDownloadState(path)
+	require.NoError(t, err)
+	assert.False(t, loaded.Native)
+	assert.Equal(t, state.YtdlpOptions, loaded.YtdlpOptions)
+}
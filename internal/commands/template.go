@@ -0,0 +1,437 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default templates of output names.
+const (
+	DefaultDownloadTemplate  = "{title}_{id}_{start}_{duration}.{ext}"
+	DefaultFrameTemplate     = "{title}_{id}_{start}.{ext}"
+	DefaultRecordTemplate    = "{title}_{id}_{start}.{ext}"
+	DefaultTimelapseTemplate = "{title}_{id}_{start}_e{every}/" +
+		"{title}_{id}_{start}_e{every}_{frame}.{ext}"
+)
+
+// Ways to handle existing output files.
+const (
+	CollisionSuffix    = "suffix"
+	CollisionSkip      = "skip"
+	CollisionOverwrite = "overwrite"
+)
+
+// Fields of output templates.
+const (
+	FieldTitle    = "title"
+	FieldID       = "id"
+	FieldChannel  = "channel"
+	FieldStart    = "start"
+	FieldEnd      = "end"
+	FieldDuration = "duration"
+	FieldSq       = "sq"
+	FieldFrame    = "frame"
+	FieldEvery    = "every"
+	FieldExt      = "ext"
+)
+
+// defaultFrameWidth is the default zero-padded width of frame numbers.
+const defaultFrameWidth = 4
+
+// OutputFlags are flags of output file names.
+type OutputFlags struct {
+	Output      string `help:"Template of output names, e.g. '{title}_{start:%Y-%m-%d}.{ext}'" short:"o"                  placeholder:"TEMPLATE"` //nolint:lll
+	OutputDir   string `help:"Directory to save output files in"                                                             type:"path"`
+	OnCollision string `help:"What to do with existing output files: ${enum}"                   default:"suffix" enum:"suffix,skip,overwrite"` //nolint:lll
+}
+
+// Template parses the output template, or the default one if not given, with
+// the available fields. Times are shown in the time zone.
+func (f *OutputFlags) Template(
+	tz *Timezone,
+	defaultTemplate string,
+	fields ...string,
+) (*OutputTemplate, error) {
+	s := f.Output
+	if s == "" {
+		s = defaultTemplate
+	}
+	t, err := ParseOutputTemplate(s, fields...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing output template: %w", err)
+	}
+	t.dir, t.tz = f.OutputDir, tz
+	return t, nil
+}
+
+// Namer returns a namer of output paths following the collision flag.
+func (f *OutputFlags) Namer() *OutputNamer {
+	return NewOutputNamer(f.OnCollision)
+}
+
+// OutputFields are values of output template fields.
+type OutputFields struct {
+	Title    string
+	ID       string
+	Channel  string
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	Sq       int
+	Frame    int
+	Every    time.Duration
+	Ext      string
+}
+
+// OutputTemplate is a template of output paths. Fields are given in braces,
+// optionally with a format after a colon:
+//
+//   - {title:N} and {channel:N}: adjusted to at most N characters;
+//   - {start:FORMAT} and {end:FORMAT}: formatted with strftime-like
+//     directives, such as %Y-%m-%d;
+//   - {sq:N} and {frame:N}: zero-padded to N digits.
+//
+// Double braces stand for literal ones.
+type OutputTemplate struct {
+	parts []templatePart
+	dir   string
+	tz    *Timezone
+}
+
+type templatePart struct {
+	literal string
+	field   string
+	format  string
+}
+
+// ParseOutputTemplate parses a template allowing the fields.
+func ParseOutputTemplate(s string, fields ...string) (*OutputTemplate, error) {
+	t := &OutputTemplate{}
+
+	var literal strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			literal.WriteByte(s[i])
+			i++
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed field at %d", i)
+			}
+			field, format, _ := strings.Cut(s[i+1:i+end], ":")
+			if !slices.Contains(fields, field) {
+				return nil, fmt.Errorf(
+					"unknown field %q, expected one of %s",
+					field,
+					strings.Join(fields, ", "),
+				)
+			}
+			if err := validateFieldFormat(field, format); err != nil {
+				return nil, err
+			}
+			if literal.Len() > 0 {
+				t.parts = append(t.parts, templatePart{literal: literal.String()})
+				literal.Reset()
+			}
+			t.parts = append(t.parts, templatePart{field: field, format: format})
+			i += end
+		case s[i] == '}':
+			return nil, fmt.Errorf("unexpected '}' at %d", i)
+		default:
+			literal.WriteByte(s[i])
+		}
+	}
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, templatePart{literal: literal.String()})
+	}
+
+	if len(t.parts) == 0 {
+		return nil, errors.New("empty template")
+	}
+
+	return t, nil
+}
+
+func validateFieldFormat(field, format string) error {
+	if format == "" {
+		return nil
+	}
+	switch field {
+	case FieldTitle, FieldChannel, FieldSq, FieldFrame:
+		if n, err := strconv.Atoi(format); err != nil || n <= 0 {
+			return fmt.Errorf("bad format of field %q, expected a positive number", field)
+		}
+	case FieldStart, FieldEnd:
+	default:
+		return fmt.Errorf("field %q has no format", field)
+	}
+	return nil
+}
+
+// Uses reports whether the template contains the field.
+func (t *OutputTemplate) Uses(field string) bool {
+	return slices.ContainsFunc(t.parts, func(p templatePart) bool {
+		return p.field == field
+	})
+}
+
+// Execute returns the output path with the field values, placed into the
+// output directory, if any.
+func (t *OutputTemplate) Execute(fields OutputFields) string {
+	return filepath.Join(t.dir, t.execute(fields))
+}
+
+// OwnDir returns the directory given by the template itself for outputs with
+// the field values, placed into the output directory, if any. It returns an
+// empty string if the template places outputs right into the output
+// directory.
+func (t *OutputTemplate) OwnDir(fields OutputFields) string {
+	dir := filepath.Dir(t.execute(fields))
+	if dir == "." {
+		return ""
+	}
+	return filepath.Join(t.dir, dir)
+}
+
+func (t *OutputTemplate) execute(fields OutputFields) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.field == "" {
+			b.WriteString(p.literal)
+			continue
+		}
+		b.WriteString(t.formatField(p.field, p.format, fields))
+	}
+	return b.String()
+}
+
+func (t *OutputTemplate) formatField(field, format string, fields OutputFields) string {
+	number := func(n int, defaultWidth int) string {
+		width := defaultWidth
+		if format != "" {
+			width, _ = strconv.Atoi(format)
+		}
+		return fmt.Sprintf("%0*d", width, n)
+	}
+	length := func() int {
+		n, _ := strconv.Atoi(format)
+		return n
+	}
+	formatTime := func(tt time.Time) string {
+		tt = t.tz.In(tt)
+		if format == "" {
+			return FormatTime(tt)
+		}
+		return Strftime(tt, format)
+	}
+
+	switch field {
+	case FieldTitle:
+		return AdjustForFilename(fields.Title, length())
+	case FieldID:
+		return fields.ID
+	case FieldChannel:
+		return AdjustForFilename(fields.Channel, length())
+	case FieldStart:
+		return formatTime(fields.Start)
+	case FieldEnd:
+		return formatTime(fields.End)
+	case FieldDuration:
+		return FormatDuration(fields.Duration)
+	case FieldSq:
+		return number(fields.Sq, 0)
+	case FieldFrame:
+		return number(fields.Frame, defaultFrameWidth)
+	case FieldEvery:
+		return FormatDuration(fields.Every)
+	case FieldExt:
+		return fields.Ext
+	}
+	return ""
+}
+
+// strftimeLayouts maps strftime directives to Go time layouts.
+var strftimeLayouts = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'b': "Jan",
+	'B': "January",
+	'd': "02",
+	'a': "Mon",
+	'A': "Monday",
+	'H': "15",
+	'I': "03",
+	'p': "PM",
+	'M': "04",
+	'S': "05",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+// Strftime formats the time with strftime-like directives: %Y, %y, %m, %b,
+// %B, %d, %a, %A, %H, %I, %p, %M, %S, %f (milliseconds), %z, %Z, %j (day of
+// year), %s (Unix time), and %% for a literal percent sign.
+func Strftime(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch c := format[i]; c {
+		case '%':
+			b.WriteByte('%')
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'f':
+			b.WriteString(t.Format(".000")[1:])
+		default:
+			if layout, ok := strftimeLayouts[c]; ok {
+				b.WriteString(t.Format(layout))
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// OutputNamer resolves collisions of output paths with existing files and
+// with other outputs of the same run.
+type OutputNamer struct {
+	onCollision string
+	taken       map[string]bool
+}
+
+// NewOutputNamer creates a namer handling collisions in the way: adding a
+// numeric suffix, skipping the output, or overwriting the file.
+func NewOutputNamer(onCollision string) *OutputNamer {
+	return &OutputNamer{
+		onCollision: onCollision,
+		taken:       make(map[string]bool),
+	}
+}
+
+// Resolve returns the path to save the output to, or false if the output
+// should be skipped. The path's parent directories are created.
+func (n *OutputNamer) Resolve(path string) (string, bool, error) {
+	if n.exists(path) {
+		switch n.onCollision {
+		case CollisionSkip:
+			slog.Info("skipping existing output", "path", path)
+			return "", false, nil
+		case CollisionOverwrite:
+		default:
+			path = n.suffixed(path)
+		}
+	}
+	n.taken[path] = true
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", false, fmt.Errorf("creating output directories: %w", err)
+	}
+
+	return path, true, nil
+}
+
+// ResolveGroup resolves paths of a group of outputs, such as timelapse frames,
+// at once, so that they are named alike. The dir is the group's own directory,
+// or an empty string if it has none. On collisions, all paths get the same
+// numeric suffix: the first free directory is taken if the group has its own
+// one, otherwise names are suffixed. Outputs to skip get empty paths. Parent
+// directories of the paths are created.
+func (n *OutputNamer) ResolveGroup(paths []string, dir string) ([]string, error) {
+	resolved := slices.Clone(paths)
+	if n.onCollision == CollisionSuffix && slices.ContainsFunc(paths, n.exists) {
+		for i := 1; ; i++ {
+			if dir != "" && n.exists(fmt.Sprintf("%s_%d", dir, i)) {
+				continue
+			}
+			var err error
+			resolved, err = suffixedGroup(paths, dir, i)
+			if err != nil {
+				return nil, err
+			}
+			if dir != "" || !slices.ContainsFunc(resolved, n.exists) {
+				break
+			}
+		}
+	}
+
+	created := make(map[string]bool)
+	for i, path := range resolved {
+		if n.onCollision == CollisionSkip && n.exists(path) {
+			slog.Info("skipping existing output", "path", path)
+			resolved[i] = ""
+			continue
+		}
+		n.taken[path] = true
+
+		if parent := filepath.Dir(path); !created[parent] {
+			if err := os.MkdirAll(parent, 0o755); err != nil {
+				return nil, fmt.Errorf("creating output directories: %w", err)
+			}
+			created[parent] = true
+		}
+	}
+
+	return resolved, nil
+}
+
+// suffixedGroup returns paths of a group with the numeric suffix added to their
+// directory, if given, or to their names.
+func suffixedGroup(paths []string, dir string, suffix int) ([]string, error) {
+	suffixed := make([]string, len(paths))
+	for i, path := range paths {
+		if dir == "" {
+			ext := filepath.Ext(path)
+			base := strings.TrimSuffix(path, ext)
+			suffixed[i] = fmt.Sprintf("%s_%d%s", base, suffix, ext)
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, fmt.Errorf("building output path: %w", err)
+		}
+		suffixed[i] = filepath.Join(fmt.Sprintf("%s_%d", dir, suffix), rel)
+	}
+	return suffixed, nil
+}
+
+// suffixed returns the first free path with a numeric suffix.
+func (n *OutputNamer) suffixed(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if !n.exists(candidate) {
+			return candidate
+		}
+	}
+}
+
+// exists reports whether the path is taken. Paths with the yt-dlp's
+// '%(ext)s' placeholder match files with any extension.
+func (n *OutputNamer) exists(path string) bool {
+	if n.taken[path] {
+		return true
+	}
+	if !strings.Contains(path, ytdlpExtPlaceholder) {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	return len(findYtdlpOutputs(path)) > 0
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOutputFields = OutputFields{
+	Title:    "Stream title",
+	ID:       "abcdefgh123",
+	Channel:  "Test channel",
+	Start:    time.Date(2026, 1, 2, 10, 20, 30, 500_000_000, time.UTC),
+	End:      time.Date(2026, 1, 2, 10, 21, 0, 0, time.UTC),
+	Duration: 30 * time.Second,
+	Sq:       42,
+	Frame:    7,
+	Every:    time.Minute,
+	Ext:      "mp4",
+}
+
+func TestOutputTemplate_Execute(t *testing.T) {
+	t.Parallel()
+
+	allFields := []string{
+		FieldTitle, FieldID, FieldChannel, FieldStart, FieldEnd,
+		FieldDuration, FieldSq, FieldFrame, FieldEvery, FieldExt,
+	}
+
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{
+			template: DefaultDownloadTemplate,
+			expected: "Stream-title_abcdefgh123_20260102T102030+00_30s.mp4",
+		},
+		{
+			template: DefaultTimelapseTemplate,
+			expected: "Stream-title_abcdefgh123_20260102T102030+00_e1m/" +
+				"Stream-title_abcdefgh123_20260102T102030+00_e1m_0007.mp4",
+		},
+		{
+			template: "{channel:4}/{start:%Y-%m-%d_%H.%M.%S.%f}--{end:%H%M}_{sq:6}.{ext}",
+			expected: "Test/2026-01-02_10.20.30.500--1021_000042.mp4",
+		},
+		{
+			template: "{{{id}}}_{frame:2}_100%",
+			expected: "{abcdefgh123}_07_100%",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			t.Parallel()
+			template, err := ParseOutputTemplate(tc.template, allFields...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, template.Execute(testOutputFields))
+		})
+	}
+}
+
+func TestParseOutputTemplate_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "{title", "title}", "{frame}", "{title:long}", "{ext:3}"} {
+		_, err := ParseOutputTemplate(s, FieldTitle, FieldExt)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestOutputNamer_Resolve(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "out.mp4")
+	require.NoError(t, os.WriteFile(existing, nil, 0o600))
+
+	path, ok, err := NewOutputNamer(CollisionOverwrite).Resolve(existing)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, existing, path)
+
+	_, ok, err = NewOutputNamer(CollisionSkip).Resolve(existing)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	namer := NewOutputNamer(CollisionSuffix)
+	path, ok, err = namer.Resolve(existing)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "out_1.mp4"), path)
+
+	// Outputs of the same run collide as well
+	path, _, err = namer.Resolve(existing)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "out_2.mp4"), path)
+
+	// Directories are created
+	nested := filepath.Join(dir, "a", "b", "out.%(ext)s")
+	path, ok, err = namer.Resolve(nested)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, nested, path)
+	assert.DirExists(t, filepath.Join(dir, "a", "b"))
+}
+
+func TestOutputNamer_ResolveGroup(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	frames := func(dir, suffix string) []string {
+		return []string{
+			filepath.Join(dir, "frame_0000"+suffix+".jpg"),
+			filepath.Join(dir, "frame_0001"+suffix+".jpg"),
+		}
+	}
+
+	// Frames in their own directory, one of them existing
+	dir := filepath.Join(root, "timelapse")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(frames(dir, "")[1], nil, 0o600))
+
+	paths, err := NewOutputNamer(CollisionSuffix).ResolveGroup(frames(dir, ""), dir)
+	require.NoError(t, err)
+	assert.Equal(t, frames(dir+"_1", ""), paths)
+	assert.DirExists(t, dir+"_1")
+
+	paths, err = NewOutputNamer(CollisionSkip).ResolveGroup(frames(dir, ""), dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{frames(dir, "")[0], ""}, paths)
+
+	paths, err = NewOutputNamer(CollisionOverwrite).ResolveGroup(frames(dir, ""), dir)
+	require.NoError(t, err)
+	assert.Equal(t, frames(dir, ""), paths)
+
+	// Frames without their own directory
+	paths, err = NewOutputNamer(CollisionSuffix).ResolveGroup(frames(dir, ""), "")
+	require.NoError(t, err)
+	assert.Equal(t, frames(dir, "_1"), paths)
+
+	// No collisions
+	paths, err = NewOutputNamer(CollisionSuffix).ResolveGroup(frames(root, ""), "")
+	require.NoError(t, err)
+	assert.Equal(t, frames(root, ""), paths)
+}
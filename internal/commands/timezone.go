@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+)
+
+// Special values of the time zone flag.
+const (
+	LocalTimezone  = "local"
+	StreamTimezone = "stream"
+)
+
+// Timezone is the time zone to read times without an offset and to show times
+// in. A nil time zone reads times without an offset in the local time zone and
+// shows other times as they are.
+type Timezone struct {
+	// location is the time zone to show times in. If nil, times are shown in
+	// their own time zones.
+	location *time.Location
+	// followsStream tells to use the stream's time zone once it is known.
+	followsStream bool
+}
+
+// NewTimezone returns the time zone by name: an IANA name, 'local', or
+// 'stream' for the stream's local time zone. The stream's time zone is set
+// with UseStream, and until then times are handled as without a name.
+func NewTimezone(name string) (*Timezone, error) {
+	tz := &Timezone{}
+
+	switch name {
+	case "":
+	case LocalTimezone:
+		tz.location = time.Local //nolint:gosmopolitan
+	case StreamTimezone:
+		tz.followsStream = true
+	default:
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("loading time zone: %w", err)
+		}
+		tz.location = loc
+	}
+
+	return tz, nil
+}
+
+// Location returns the time zone to read times without an offset in.
+func (tz *Timezone) Location() *time.Location {
+	if tz == nil || tz.location == nil {
+		return time.Local //nolint:gosmopolitan
+	}
+	return tz.location
+}
+
+// In returns the time in the time zone to show times in.
+func (tz *Timezone) In(t time.Time) time.Time {
+	if tz == nil || tz.location == nil {
+		return t
+	}
+	return t.In(tz.location)
+}
+
+// FollowsStream reports whether the stream's time zone is requested. Times
+// read before it is known should be read again after UseStream.
+func (tz *Timezone) FollowsStream() bool {
+	return tz != nil && tz.followsStream
+}
+
+// UseStream switches to the stream's time zone, if requested and known.
+func (tz *Timezone) UseStream(videoInfo info.VideoInformation) error {
+	if !tz.FollowsStream() {
+		return nil
+	}
+	if videoInfo.Timezone == "" {
+		slog.Warn("stream time zone is unknown, keeping the default one")
+		return nil
+	}
+
+	loc, err := time.LoadLocation(videoInfo.Timezone)
+	if err != nil {
+		return fmt.Errorf("loading stream time zone: %w", err)
+	}
+	tz.location = loc
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+)
+
+func TestNewTimezone(t *testing.T) {
+	t.Parallel()
+
+	tz, err := NewTimezone("Asia/Kolkata")
+	require.NoError(t, err)
+
+	at := time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)
+	assert.Equal(t, "20260102T155030+0530", FormatTime(tz.In(at)))
+	assert.Equal(t, "2026-01-02 15:50", Strftime(tz.In(at), "%Y-%m-%d %H:%M"))
+
+	start, _, err := ParseInterval("2026-01-02T15:50:30/30s", tz)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(start.(time.Time)))
+
+	_, err = NewTimezone("Nowhere/Unknown")
+	require.Error(t, err)
+}
+
+func TestTimezone_Default(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)
+	for _, tz := range []*Timezone{nil, {}} {
+		assert.Equal(t, at, tz.In(at))
+		assert.Equal(t, time.Local, tz.Location()) //nolint:gosmopolitan
+	}
+}
+
+func TestTimezone_UseStream(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)
+
+	tz, err := NewTimezone(StreamTimezone)
+	require.NoError(t, err)
+	assert.True(t, tz.FollowsStream())
+	assert.Equal(t, "20260102T102030+00", FormatTime(tz.In(at)))
+
+	require.NoError(t, tz.UseStream(info.VideoInformation{}))
+	assert.Equal(t, "20260102T102030+00", FormatTime(tz.In(at)))
+
+	require.NoError(t, tz.UseStream(info.VideoInformation{Timezone: "Asia/Tokyo"}))
+	assert.Equal(t, "20260102T192030+09", FormatTime(tz.In(at)))
+
+	start, _, err := ParseInterval("2026-01-02T19:20:30/30s", tz)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(start.(time.Time)))
+
+	other, err := NewTimezone("UTC")
+	require.NoError(t, err)
+	require.NoError(t, other.UseStream(info.VideoInformation{Timezone: "Asia/Tokyo"}))
+	assert.Equal(t, "20260102T102030+00", FormatTime(other.In(at)))
+}
+
+func TestCheckFetcher_StreamTimezone(t *testing.T) {
+	t.Parallel()
+
+	tz, err := NewTimezone("stream")
+	require.NoError(t, err)
+
+	flags := &CommonFlags{Fetcher: "static"}
+	require.ErrorContains(t, flags.CheckFetcher(tz), "only known with the file fetcher")
+
+	flags = &CommonFlags{Fetcher: "file"}
+	require.NoError(t, flags.CheckFetcher(tz))
+
+	tz, err = NewTimezone("Asia/Tokyo")
+	require.NoError(t, err)
+	flags = &CommonFlags{Fetcher: "static"}
+	require.NoError(t, flags.CheckFetcher(tz))
+}
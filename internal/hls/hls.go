@@ -0,0 +1,181 @@
+package hls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+)
+
+const (
+	// version 7 is required for fMP4 segments in media playlists
+	playlistVersion = 7
+	audioGroupID    = "audio"
+	segmentMediaURL = "segments/itag/%s/sq/%d"
+	segmentInitURL  = "segments/itag/%s/sq/%d/init"
+	mp4MimeSuffix   = "/mp4"
+)
+
+// Fallback bitrates, in bits per second, for streams with unknown bitrates.
+const (
+	fallbackAudioBitrate = 128_000
+	fallbackVideoBitrate = 2_500_000
+)
+
+type PlaylistType string
+
+const (
+	// PlaylistVOD is a playlist of a bounded interval.
+	PlaylistVOD PlaylistType = "VOD"
+	// PlaylistEvent is a playlist growing as new segments become available.
+	PlaylistEvent PlaylistType = "EVENT"
+)
+
+type MediaOptions struct {
+	BaseURL         string
+	Itag            string
+	Type            PlaylistType
+	StartNumber     int
+	SegmentCount    int
+	SegmentDuration time.Duration
+	// StartTime is the walltime of the first segment.
+	StartTime time.Time
+	// Discontinuities are segments following gaps in the stream timeline.
+	Discontinuities []Discontinuity
+}
+
+// Discontinuity marks the first segment after a gap in the stream timeline.
+type Discontinuity struct {
+	SequenceNumber int
+	// StartTime is the walltime of the segment.
+	StartTime time.Time
+}
+
+// ComposeMaster composes a master playlist referring to media playlists of all
+// fMP4 streams. The mediaURL function returns the media playlist URL of a
+// stream.
+func ComposeMaster(videoInfo info.VideoInformation, mediaURL func(itag string) string) (string, error) {
+	var b strings.Builder
+
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", playlistVersion)
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	audioBitrate := 0
+	audioCodecs := ""
+	for _, stream := range videoInfo.AudioStreams {
+		if !isMP4(stream.MimeType) {
+			continue
+		}
+		isDefault := "NO"
+		if audioCodecs == "" {
+			isDefault = "YES"
+			audioCodecs = stream.Codecs
+		}
+		audioBitrate = max(audioBitrate, bitrate(stream.CommonStream, fallbackAudioBitrate))
+		fmt.Fprintf(
+			&b,
+			"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=%q,NAME=%q,DEFAULT=%s,AUTOSELECT=YES,URI=%q\n",
+			audioGroupID,
+			stream.Itag,
+			isDefault,
+			mediaURL(stream.Itag),
+		)
+	}
+
+	count := 0
+	for _, stream := range videoInfo.VideoStreams {
+		if !isMP4(stream.MimeType) {
+			continue
+		}
+		count++
+
+		codecs := stream.Codecs
+		audioAttr := ""
+		if audioCodecs != "" {
+			codecs += "," + audioCodecs
+			audioAttr = fmt.Sprintf(",AUDIO=%q", audioGroupID)
+		}
+		fmt.Fprintf(
+			&b,
+			"#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=%q,RESOLUTION=%dx%d,FRAME-RATE=%d%s\n",
+			bitrate(stream.CommonStream, fallbackVideoBitrate)+audioBitrate,
+			codecs,
+			stream.Width,
+			stream.Height,
+			stream.FrameRate,
+			audioAttr,
+		)
+		b.WriteString(mediaURL(stream.Itag) + "\n")
+	}
+
+	if count == 0 {
+		return "", errors.New("no fMP4 video streams")
+	}
+
+	return b.String(), nil
+}
+
+// ComposeMedia composes a media playlist of a stream.
+func ComposeMedia(opts MediaOptions) (string, error) {
+	if opts.SegmentCount < 0 {
+		return "", fmt.Errorf("negative segment count: %d", opts.SegmentCount)
+	}
+
+	var b strings.Builder
+
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", playlistVersion)
+	fmt.Fprintf(
+		&b,
+		"#EXT-X-TARGETDURATION:%d\n",
+		int(math.Ceil(opts.SegmentDuration.Seconds())),
+	)
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", opts.StartNumber)
+	fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", opts.Type)
+	b.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	fmt.Fprintf(
+		&b,
+		"#EXT-X-MAP:URI=%q\n",
+		opts.BaseURL+fmt.Sprintf(segmentInitURL, opts.Itag, opts.StartNumber),
+	)
+	writeProgramDateTime(&b, opts.StartTime)
+
+	discontinuities := make(map[int]time.Time, len(opts.Discontinuities))
+	for _, d := range opts.Discontinuities {
+		discontinuities[d.SequenceNumber] = d.StartTime
+	}
+
+	for sq := opts.StartNumber; sq < opts.StartNumber+opts.SegmentCount; sq++ {
+		if startTime, ok := discontinuities[sq]; ok && sq != opts.StartNumber {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+			writeProgramDateTime(&b, startTime)
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", opts.SegmentDuration.Seconds())
+		b.WriteString(opts.BaseURL + fmt.Sprintf(segmentMediaURL, opts.Itag, sq) + "\n")
+	}
+
+	if opts.Type == PlaylistVOD {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	return b.String(), nil
+}
+
+func writeProgramDateTime(b *strings.Builder, t time.Time) {
+	fmt.Fprintf(b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", t.UTC().Format("2006-01-02T15:04:05.000Z"))
+}
+
+func isMP4(mimeType string) bool {
+	return strings.HasSuffix(mimeType, mp4MimeSuffix)
+}
+
+func bitrate(stream info.CommonStream, fallback int) int {
+	if stream.Bitrate > 0 {
+		return stream.Bitrate
+	}
+	return fallback
+}
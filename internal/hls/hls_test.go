@@ -0,0 +1,126 @@
+package hls_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/hls"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func TestComposeMaster(t *testing.T) {
+	t.Parallel()
+
+	videoInfo, _, err := (&testutil.MockFetcher{}).FetchInfo(context.Background())
+	require.NoError(t, err)
+	videoInfo.VideoStreams[1].Bitrate = 4_000_000
+
+	expected := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="140",DEFAULT=YES,AUTOSELECT=YES,URI="hls/1--3/itag/140"
+#EXT-X-STREAM-INF:BANDWIDTH=2628000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=1280x720,FRAME-RATE=30,AUDIO="audio"
+hls/1--3/itag/136
+#EXT-X-STREAM-INF:BANDWIDTH=4128000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080,FRAME-RATE=30,AUDIO="audio"
+hls/1--3/itag/137
+`
+	actual, err := hls.ComposeMaster(*videoInfo, func(itag string) string {
+		return "hls/1--3/itag/" + itag
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestComposeMedia(t *testing.T) {
+	t.Parallel()
+
+	opts := hls.MediaOptions{
+		BaseURL:         "http://localhost:8080/",
+		Itag:            "137",
+		StartNumber:     10,
+		SegmentCount:    2,
+		SegmentDuration: 2 * time.Second,
+		StartTime:       time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC),
+	}
+
+	header := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:10
+`
+	body := `#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-MAP:URI="http://localhost:8080/segments/itag/137/sq/10/init"
+#EXT-X-PROGRAM-DATE-TIME:2026-01-02T10:20:30.000Z
+#EXTINF:2.000,
+http://localhost:8080/segments/itag/137/sq/10
+#EXTINF:2.000,
+http://localhost:8080/segments/itag/137/sq/11
+`
+
+	testCases := []struct {
+		name         string
+		playlistType hls.PlaylistType
+		expected     string
+	}{
+		{
+			name:         "vod",
+			playlistType: hls.PlaylistVOD,
+			expected:     header + "#EXT-X-PLAYLIST-TYPE:VOD\n" + body + "#EXT-X-ENDLIST\n",
+		},
+		{
+			name:         "event",
+			playlistType: hls.PlaylistEvent,
+			expected:     header + "#EXT-X-PLAYLIST-TYPE:EVENT\n" + body,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := opts
+			opts.Type = tc.playlistType
+
+			actual, err := hls.ComposeMedia(opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestComposeMedia_Discontinuities(t *testing.T) {
+	t.Parallel()
+
+	startTime := time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)
+	actual, err := hls.ComposeMedia(hls.MediaOptions{
+		BaseURL:         "http://localhost:8080/",
+		Itag:            "137",
+		Type:            hls.PlaylistVOD,
+		StartNumber:     10,
+		SegmentCount:    3,
+		SegmentDuration: 2 * time.Second,
+		StartTime:       startTime,
+		Discontinuities: []hls.Discontinuity{
+			{SequenceNumber: 11, StartTime: startTime.Add(time.Minute)},
+		},
+	})
+	require.NoError(t, err)
+
+	expected := `#EXT-X-PROGRAM-DATE-TIME:2026-01-02T10:20:30.000Z
+#EXTINF:2.000,
+http://localhost:8080/segments/itag/137/sq/10
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2026-01-02T10:21:30.000Z
+#EXTINF:2.000,
+http://localhost:8080/segments/itag/137/sq/11
+#EXTINF:2.000,
+http://localhost:8080/segments/itag/137/sq/12
+#EXT-X-ENDLIST
+`
+	assert.True(t, strings.HasSuffix(actual, expected), actual)
+}
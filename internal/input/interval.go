@@ -34,21 +34,34 @@ const (
 
 type ParserResult = gomme.Result[MomentValue, string]
 
-var intervalPart = gomme.Alternative(
-	parseExpression,               // e.g., 2026-01-02T10:20:30+00 - 30s
-	parseDateAndTime,              // e.g., 2026-01-02T10:20:30+00
-	parseDuration,                 // e.g., 1d2h3m4s
-	parseUnixTimestamp,            // e.g., @1767349230
-	parseKeyword(NowKeyword),      // now
-	parseKeyword(EarliestKeyword), // earliest
-	parseSequenceNumber,           // e.g., 123
-)
+// intervalPart parses a moment value. Dates and times without an offset are
+// read in the location.
+func intervalPart(loc *time.Location) gomme.Parser[string, MomentValue] {
+	return gomme.Alternative(
+		inLocation(parseExpression, loc),  // e.g., 2026-01-02T10:20:30+00 - 30s
+		inLocation(parseDateAndTime, loc), // e.g., 2026-01-02T10:20:30+00
+		parseDuration,                     // e.g., 1d2h3m4s
+		parseUnixTimestamp,                // e.g., @1767349230
+		parseKeyword(NowKeyword),          // now
+		parseKeyword(EarliestKeyword),     // earliest
+		parseSequenceNumber,               // e.g., 123
+	)
+}
 
+// ParseInterval parses an interval, reading dates and times without an offset
+// in the local time zone.
 func ParseInterval(input string) (MomentValue, MomentValue, error) {
+	return ParseIntervalIn(input, time.Local) //nolint:gosmopolitan
+}
+
+// ParseIntervalIn parses an interval, reading dates and times without an
+// offset in the location.
+func ParseIntervalIn(input string, loc *time.Location) (MomentValue, MomentValue, error) {
+	part := intervalPart(loc)
 	result := gomme.SeparatedPair(
-		intervalPart,
+		part,
 		gomme.Alternative(gomme.Token[string]("/"), gomme.Token[string]("--")),
-		tillEnd(intervalPart),
+		tillEnd(part),
 	)(input)
 	if result.Err != nil {
 		return nil, nil, result.Err
@@ -82,14 +95,33 @@ func ParseInterval(input string) (MomentValue, MomentValue, error) {
 	return start, end, nil
 }
 
+// ParseIntervalPart parses a moment value, reading dates and times without an
+// offset in the local time zone.
 func ParseIntervalPart(input string) (MomentValue, error) {
-	result := intervalPart(input)
+	return ParseIntervalPartIn(input, time.Local) //nolint:gosmopolitan
+}
+
+// ParseIntervalPartIn parses a moment value, reading dates and times without
+// an offset in the location.
+func ParseIntervalPartIn(input string, loc *time.Location) (MomentValue, error) {
+	result := intervalPart(loc)(input)
 	if result.Err != nil {
 		return nil, result.Err
 	}
 	return result.Output, nil
 }
 
+// inLocation binds the location to a parser reading dates and times without an
+// offset in it.
+func inLocation(
+	parser func(string, *time.Location) ParserResult,
+	loc *time.Location,
+) func(string) ParserResult {
+	return func(input string) ParserResult {
+		return parser(input, loc)
+	}
+}
+
 func parseKeyword(keyword MomentKeyword) func(string) ParserResult {
 	return func(input string) ParserResult {
 		return gomme.Map(
@@ -110,7 +142,7 @@ func parseSequenceNumber(input string) ParserResult {
 	)(input)
 }
 
-func parseDateAndTime(input string) ParserResult {
+func parseDateAndTime(input string, location *time.Location) ParserResult {
 	digits := func(n uint) gomme.Parser[string, int] {
 		return gomme.Map(
 			gomme.Take[string](n),
@@ -215,10 +247,14 @@ func parseDateAndTime(input string) ParserResult {
 		),
 	)
 
+	// Dates and times are parsed in UTC and then re-zoned keeping the wall
+	// clock, so that date-only inputs start at midnight in the location.
 	withLocation := func(t time.Time, loc *time.Location) time.Time {
-		newTime := t.In(loc)
-		_, offsetSeconds := newTime.Zone()
-		return newTime.Add(-time.Duration(offsetSeconds) * time.Second)
+		return time.Date(
+			t.Year(), t.Month(), t.Day(),
+			t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+			loc,
+		)
 	}
 	offsetted := func(
 		t gomme.Parser[string, MomentValue],
@@ -232,7 +268,7 @@ func parseDateAndTime(input string) ParserResult {
 				if loc != nil {
 					return withLocation(tt, loc), nil
 				}
-				return withLocation(tt, time.Local), nil //nolint:gosmopolitan
+				return withLocation(tt, location), nil
 			},
 		)
 	}
@@ -311,12 +347,12 @@ func parseDuration(input string) ParserResult {
 	)(input)
 }
 
-func parseExpression(input string) ParserResult {
+func parseExpression(input string, location *time.Location) ParserResult {
 	// Parse left operand
 	leftResult := gomme.Terminated(
 		gomme.Alternative(
 			parseKeyword(NowKeyword),
-			parseDateAndTime,
+			inLocation(parseDateAndTime, location),
 			parseUnixTimestamp,
 			parseSequenceNumber,
 		),
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -385,6 +386,64 @@ func TestParseIntervalPart_Expressions(t *testing.T) {
 	}
 }
 
+func TestParseIntervalPartIn(t *testing.T) {
+	t.Parallel()
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		input    string
+		wantTime time.Time
+	}{
+		{
+			name:     "standard time",
+			input:    "2026-01-02T10:20:30",
+			wantTime: time.Date(2026, 1, 2, 15, 20, 30, 0, time.UTC),
+		},
+		{
+			name:     "daylight saving time",
+			input:    "2026-07-02T10:20:30",
+			wantTime: time.Date(2026, 7, 2, 14, 20, 30, 0, time.UTC),
+		},
+		{
+			name:     "date only",
+			input:    "2026-01-02",
+			wantTime: time.Date(2026, 1, 2, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "date only in daylight saving time",
+			input:    "2026-07-02",
+			wantTime: time.Date(2026, 7, 2, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "date only on transition day",
+			input:    "2026-03-08T12:00",
+			wantTime: time.Date(2026, 3, 8, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "explicit offset",
+			input:    "2026-01-02T10:20:30+01",
+			wantTime: time.Date(2026, 1, 2, 9, 20, 30, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			value, err := input.ParseIntervalPartIn(tc.input, newYork)
+			if err != nil {
+				t.Fatalf("should not fail, got %v", err)
+			}
+			if !value.(time.Time).Equal(tc.wantTime) {
+				t.Fatalf("want %v, got %v", tc.wantTime, value)
+			}
+		})
+	}
+}
+
 func TestParseInterval(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -3,6 +3,7 @@ package mpd
 import (
 	"encoding/xml"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,15 @@ const (
 	mpdProfilesStatic = "urn:mpeg:dash:profile:isoff-main:2011"
 	mpdProfilesLive   = "urn:mpeg:dash:profile:isoff-live:2011"
 	segmentMediaURL   = "segments/itag/$RepresentationID$/sq/$Number$"
+	// timeURL is the path of the server time endpoint relative to the base URL.
+	timeURL         = "time"
+	utcTimingScheme = "urn:mpeg:dash:utc:http-iso:2014"
+	// chapterScheme identifies event streams of chapters.
+	chapterScheme = "urn:ypb:chapter"
+	// timescale is the number of timeline units per second (milliseconds).
+	timescale int64 = 1000
+	// dateTimeLayout is an xs:dateTime layout with milliseconds.
+	dateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
 )
 
 type CommonOptions struct {
@@ -28,26 +38,54 @@ type CommonOptions struct {
 type StaticOptions struct {
 	CommonOptions
 	MediaDuration time.Duration
-	SegmentCount  int
+	Timeline      []SegmentRun
+	Chapters      []Chapter
+}
+
+// Chapter is a titled part of the presentation. Offset is the time of its
+// start from the start of the presentation.
+type Chapter struct {
+	Offset   time.Duration
+	Duration time.Duration
+	Title    string
+}
+
+// SegmentRun describes a run of consecutive segments without gaps in between.
+// Offset is the walltime offset of the first segment in the run from the start
+// of the presentation.
+type SegmentRun struct {
+	Offset time.Duration
+	Count  int
 }
 
 type DynamicOptions struct {
 	CommonOptions
-	AvailabilityStartTime time.Time
-	TimeShiftBufferDepth  time.Duration
+	AvailabilityStartTime      time.Time
+	TimeShiftBufferDepth       time.Duration
+	MinimumUpdatePeriod        time.Duration
+	SuggestedPresentationDelay time.Duration
 }
 
 type MPD struct {
-	XMLName                   xml.Name            `xml:"MPD"`
-	Xmlns                     string              `xml:"xmlns,attr"`
-	Profiles                  string              `xml:"profiles,attr"`
-	Type                      string              `xml:"type,attr"`
-	AvailabilityStartTime     string              `xml:"availabilityStartTime,attr,omitempty"`
-	MediaPresentationDuration string              `xml:"mediaPresentationDuration,attr,omitempty"`
-	TimeShiftBufferDepth      string              `xml:"timeShiftBufferDepth,attr,omitempty"`
-	ProgramInformation        *ProgramInformation `xml:"ProgramInformation"`
-	BaseURL                   string              `xml:"BaseURL"`
-	Periods                   []Period            `xml:"Period"`
+	XMLName                    xml.Name            `xml:"MPD"`
+	Xmlns                      string              `xml:"xmlns,attr"`
+	Profiles                   string              `xml:"profiles,attr"`
+	Type                       string              `xml:"type,attr"`
+	AvailabilityStartTime      string              `xml:"availabilityStartTime,attr,omitempty"`
+	MediaPresentationDuration  string              `xml:"mediaPresentationDuration,attr,omitempty"`
+	TimeShiftBufferDepth       string              `xml:"timeShiftBufferDepth,attr,omitempty"`
+	MinimumUpdatePeriod        string              `xml:"minimumUpdatePeriod,attr,omitempty"`
+	SuggestedPresentationDelay string              `xml:"suggestedPresentationDelay,attr,omitempty"`
+	ProgramInformation         *ProgramInformation `xml:"ProgramInformation"`
+	BaseURL                    string              `xml:"BaseURL"`
+	Periods                    []Period            `xml:"Period"`
+	UTCTiming                  *UTCTiming          `xml:"UTCTiming"`
+}
+
+// UTCTiming refers players to a clock to synchronize with.
+type UTCTiming struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
 }
 
 type ProgramInformation struct {
@@ -57,9 +95,23 @@ type ProgramInformation struct {
 }
 
 type Period struct {
+	EventStreams   []EventStream   `xml:"EventStream"`
 	AdaptationSets []AdaptationSet `xml:"AdaptationSet"`
 }
 
+type EventStream struct {
+	SchemeIDURI string  `xml:"schemeIdUri,attr"`
+	Timescale   string  `xml:"timescale,attr"`
+	Events      []Event `xml:"Event"`
+}
+
+type Event struct {
+	ID               int    `xml:"id,attr"`
+	PresentationTime string `xml:"presentationTime,attr"`
+	Duration         string `xml:"duration,attr,omitempty"`
+	Message          string `xml:",chardata"`
+}
+
 type AdaptationSet struct {
 	ID              int              `xml:"id,attr"`
 	MimeType        string           `xml:"mimeType,attr"`
@@ -100,6 +152,9 @@ func ComposeStatic(opts StaticOptions, videoInfo info.VideoInformation) (string,
 	m.Type = "static"
 	m.Profiles = mpdProfilesStatic
 	m.MediaPresentationDuration = formatDuration(opts.MediaDuration)
+	if len(opts.Chapters) > 0 {
+		m.Periods[0].EventStreams = []EventStream{buildChapterEventStream(opts.Chapters)}
+	}
 	m.Periods[0].AdaptationSets = buildAdaptationSets(
 		buildStaticSegmentTemplate(opts),
 		videoInfo,
@@ -111,10 +166,16 @@ func ComposeDynamic(opts DynamicOptions, videoInfo info.VideoInformation) (strin
 	m := newMPD(opts.BaseURL, videoInfo)
 	m.Type = "dynamic"
 	m.Profiles = mpdProfilesLive
-	m.AvailabilityStartTime = opts.AvailabilityStartTime.UTC().Format(time.RFC3339)
+	m.AvailabilityStartTime = opts.AvailabilityStartTime.UTC().Format(dateTimeLayout)
 	if opts.TimeShiftBufferDepth > 0 {
 		m.TimeShiftBufferDepth = formatDuration(opts.TimeShiftBufferDepth)
 	}
+	if opts.MinimumUpdatePeriod > 0 {
+		m.MinimumUpdatePeriod = formatDuration(opts.MinimumUpdatePeriod)
+	}
+	if opts.SuggestedPresentationDelay > 0 {
+		m.SuggestedPresentationDelay = formatDuration(opts.SuggestedPresentationDelay)
+	}
 	m.Periods[0].AdaptationSets = buildAdaptationSets(
 		buildDynamicSegmentTemplate(opts),
 		videoInfo,
@@ -131,6 +192,10 @@ func newMPD(baseURL string, videoInfo info.VideoInformation) MPD {
 			Source: urlutil.BuildVideoLiveURL(videoInfo.ID),
 		},
 		Periods: []Period{{}},
+		UTCTiming: &UTCTiming{
+			SchemeIDURI: utcTimingScheme,
+			Value:       strings.TrimSuffix(baseURL, "/") + "/" + timeURL,
+		},
 	}
 }
 
@@ -164,30 +229,64 @@ func buildAdaptationSets(
 	return period.AdaptationSets
 }
 
+// buildChapterEventStream builds an event stream with an event per chapter,
+// timed relative to the start of the period.
+func buildChapterEventStream(chapters []Chapter) EventStream {
+	stream := EventStream{
+		SchemeIDURI: chapterScheme,
+		Timescale:   strconv.FormatInt(timescale, 10),
+	}
+	for i, chapter := range chapters {
+		event := Event{
+			ID:               i,
+			PresentationTime: strconv.FormatInt(chapter.Offset.Milliseconds(), 10),
+			Message:          chapter.Title,
+		}
+		if chapter.Duration > 0 {
+			event.Duration = strconv.FormatInt(chapter.Duration.Milliseconds(), 10)
+		}
+		stream.Events = append(stream.Events, event)
+	}
+	return stream
+}
+
 func baseSegmentTemplate(opts CommonOptions) SegmentTemplate {
-	timescale := time.Second.Milliseconds()
 	return SegmentTemplate{
 		Media:                  segmentMediaURL,
 		StartNumber:            opts.StartNumber,
 		Timescale:              strconv.FormatInt(timescale, 10),
-		PresentationTimeOffset: fmt.Sprintf("%.0f", opts.PTS*float64(timescale)),
+		PresentationTimeOffset: strconv.FormatInt(presentationTimeOffset(opts), 10),
 	}
 }
 
 func buildStaticSegmentTemplate(opts StaticOptions) SegmentTemplate {
 	t := baseSegmentTemplate(opts.CommonOptions)
-	t.SegmentTimeline = &SegmentTimeline{
-		Timeline: []S{
-			{
-				T: t.PresentationTimeOffset,
-				D: strconv.FormatInt(opts.SegmentDuration.Milliseconds(), 10),
-				R: strconv.Itoa(opts.SegmentCount - 1),
-			},
-		},
+
+	pto := presentationTimeOffset(opts.CommonOptions)
+	duration := opts.SegmentDuration.Milliseconds()
+
+	t.SegmentTimeline = &SegmentTimeline{}
+	next := pto
+	for _, run := range opts.Timeline {
+		// Runs never overlap: a run starting earlier than the previous one ends
+		// (e.g., after the timeline catches up following a gap) is placed right
+		// after it.
+		start := max(pto+run.Offset.Milliseconds(), next)
+		t.SegmentTimeline.Timeline = append(t.SegmentTimeline.Timeline, S{
+			T: strconv.FormatInt(start, 10),
+			D: strconv.FormatInt(duration, 10),
+			R: strconv.Itoa(run.Count - 1),
+		})
+		next = start + int64(run.Count)*duration
 	}
+
 	return t
 }
 
+func presentationTimeOffset(opts CommonOptions) int64 {
+	return int64(math.RoundToEven(opts.PTS * float64(timescale)))
+}
+
 func buildDynamicSegmentTemplate(opts DynamicOptions) SegmentTemplate {
 	t := baseSegmentTemplate(opts.CommonOptions)
 	t.Duration = strconv.FormatInt(opts.SegmentDuration.Milliseconds(), 10)
@@ -0,0 +1,55 @@
+// Package cache provides caches for segment metadata.
+//
+// Mappings between sequence numbers and segment metadata never change once
+// segments are ingested, so cached entries never expire and can be shared
+// between requests and process restarts.
+package cache
+
+import (
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+// Key identifies segment metadata of a specific stream and itag.
+type Key struct {
+	VideoID        string
+	Itag           string
+	SequenceNumber int
+}
+
+// Cache defines the interface for segment metadata caches.
+type Cache interface {
+	Get(key Key) (*segment.Metadata, bool)
+	Put(key Key, metadata *segment.Metadata) error
+}
+
+// Tiered combines several caches, from the fastest to the slowest one.
+type Tiered []Cache
+
+var _ Cache = Tiered(nil)
+
+// Get looks up caches in order. On a hit, the entry is also stored in all
+// preceding caches.
+func (t Tiered) Get(key Key) (*segment.Metadata, bool) {
+	for i, c := range t {
+		metadata, ok := c.Get(key)
+		if !ok {
+			continue
+		}
+		for _, previous := range t[:i] {
+			_ = previous.Put(key, metadata)
+		}
+		return metadata, true
+	}
+	return nil, false
+}
+
+// Put stores an entry in all caches and returns the first encountered error.
+func (t Tiered) Put(key Key, metadata *segment.Metadata) error {
+	var firstErr error
+	for _, c := range t {
+		if err := c.Put(key, metadata); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
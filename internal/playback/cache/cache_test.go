@@ -0,0 +1,111 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/playback/cache"
+	"github.com/xymaxim/ypb/internal/playback/segment"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func makeEntry(sq int) (cache.Key, *segment.Metadata) {
+	key := cache.Key{
+		VideoID:        testutil.TestVideoID,
+		Itag:           "140",
+		SequenceNumber: sq,
+	}
+	metadata := &segment.Metadata{
+		SequenceNumber:    sq,
+		IngestionWalltime: time.Date(2026, 1, 2, 10, 20, 30, 123000, time.UTC),
+		Duration:          2 * time.Second,
+	}
+	return key, metadata
+}
+
+func TestMemoryCache_Eviction(t *testing.T) {
+	t.Parallel()
+
+	c := cache.NewMemoryCache(2)
+	for sq := range 2 {
+		require.NoError(t, c.Put(makeEntry(sq)))
+	}
+
+	// Touch the oldest entry to make it the most recently used one
+	key0, _ := makeEntry(0)
+	_, ok := c.Get(key0)
+	require.True(t, ok)
+
+	require.NoError(t, c.Put(makeEntry(2)))
+	assert.Equal(t, 2, c.Len())
+
+	key1, _ := makeEntry(1)
+	_, ok = c.Get(key1)
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = c.Get(key0)
+	assert.True(t, ok)
+}
+
+func TestDiskCache_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	c, err := cache.NewDiskCache(t.TempDir())
+	require.NoError(t, err)
+
+	key, want := makeEntry(123)
+	_, ok := c.Get(key)
+	require.False(t, ok)
+
+	require.NoError(t, c.Put(key, want))
+
+	got, ok := c.Get(key)
+	require.True(t, ok)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("metadata mismatch %s", testutil.PrintWantGot(diff))
+	}
+}
+
+func TestDiskCache_BadKeys(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c, err := cache.NewDiskCache(filepath.Join(dir, "cache"))
+	require.NoError(t, err)
+
+	_, metadata := makeEntry(1)
+	for _, key := range []cache.Key{
+		{VideoID: "../../escape", Itag: "140", SequenceNumber: 1},
+		{VideoID: testutil.TestVideoID, Itag: "../140", SequenceNumber: 1},
+		{VideoID: testutil.TestVideoID, Itag: "140", SequenceNumber: -1},
+	} {
+		require.Error(t, c.Put(key, metadata), "key %+v", key)
+		_, ok := c.Get(key)
+		assert.False(t, ok)
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "nothing should be written outside the cache")
+}
+
+func TestTiered_BackfillsFasterCaches(t *testing.T) {
+	t.Parallel()
+
+	memory := cache.NewMemoryCache(0)
+	disk, err := cache.NewDiskCache(t.TempDir())
+	require.NoError(t, err)
+
+	key, want := makeEntry(1)
+	require.NoError(t, disk.Put(key, want))
+
+	got, ok := cache.Tiered{memory, disk}.Get(key)
+	require.True(t, ok)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 1, memory.Len())
+}
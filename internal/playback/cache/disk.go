@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback/segment"
+	"github.com/xymaxim/ypb/internal/urlutil"
+)
+
+// itagPattern matches itags, which are joined into paths along with video IDs.
+var itagPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// DiskCache stores entries as small JSON files under a directory, laid out as
+// <dir>/<video ID>/<itag>/<sq>.json.
+type DiskCache struct {
+	dir string
+}
+
+type diskEntry struct {
+	SequenceNumber      int   `json:"sq"`
+	IngestionWalltimeUs int64 `json:"ingestionWalltimeUs"`
+	DurationUs          int64 `json:"durationUs"`
+}
+
+var _ Cache = (*DiskCache)(nil)
+
+// NewDiskCache creates a DiskCache in dir, creating the directory if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) Get(key Key) (*segment.Metadata, bool) {
+	path, err := c.path(key)
+	if err != nil {
+		slog.Warn("reading cached metadata", "error", err)
+		return nil, false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("reading cached metadata", "error", err)
+		}
+		return nil, false
+	}
+
+	var entry diskEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		slog.Warn("parsing cached metadata", "path", path, "error", err)
+		return nil, false
+	}
+
+	return &segment.Metadata{
+		SequenceNumber:    entry.SequenceNumber,
+		IngestionWalltime: time.UnixMicro(entry.IngestionWalltimeUs).UTC(),
+		Duration:          time.Duration(entry.DurationUs) * time.Microsecond,
+	}, true
+}
+
+// Put writes an entry atomically, so concurrent readers, including other
+// processes, never see partially written files.
+func (c *DiskCache) Put(key Key, metadata *segment.Metadata) error {
+	content, err := json.Marshal(diskEntry{
+		SequenceNumber:      metadata.SequenceNumber,
+		IngestionWalltimeUs: metadata.IngestionWalltime.UnixMicro(),
+		DurationUs:          metadata.Duration.Microseconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding metadata: %w", err)
+	}
+
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".ypb-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return fmt.Errorf("moving temp file: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the path of an entry. Keys are validated first, so that they
+// never point outside the cache directory.
+func (c *DiskCache) path(key Key) (string, error) {
+	if !urlutil.IsVideoID(key.VideoID) {
+		return "", fmt.Errorf("bad video id: %q", key.VideoID)
+	}
+	if !itagPattern.MatchString(key.Itag) {
+		return "", fmt.Errorf("bad itag: %q", key.Itag)
+	}
+	if key.SequenceNumber < 0 {
+		return "", fmt.Errorf("bad sequence number: %d", key.SequenceNumber)
+	}
+
+	return filepath.Join(
+		c.dir,
+		key.VideoID,
+		key.Itag,
+		strconv.Itoa(key.SequenceNumber)+".json",
+	), nil
+}
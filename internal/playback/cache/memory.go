@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+// DefaultMemoryCapacity is the default number of entries kept in memory.
+const DefaultMemoryCapacity = 10000
+
+// MemoryCache is an in-memory cache with the least recently used eviction
+// policy. It is safe for concurrent use.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[Key]*list.Element
+	order    *list.List
+}
+
+type memoryEntry struct {
+	key      Key
+	metadata segment.Metadata
+}
+
+var _ Cache = (*MemoryCache)(nil)
+
+// NewMemoryCache creates a MemoryCache holding up to capacity entries. If
+// capacity is not positive, DefaultMemoryCapacity is used.
+func NewMemoryCache(capacity int) *MemoryCache {
+	if capacity <= 0 {
+		capacity = DefaultMemoryCapacity
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		items:    make(map[Key]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(key Key) (*segment.Metadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	metadata := element.Value.(*memoryEntry).metadata
+	return &metadata, true
+}
+
+func (c *MemoryCache) Put(key Key, metadata *segment.Metadata) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*memoryEntry).metadata = *metadata
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&memoryEntry{key: key, metadata: *metadata})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryEntry).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of cached entries.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
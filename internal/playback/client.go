@@ -14,6 +14,28 @@ import (
 	"github.com/xymaxim/ypb/internal/urlutil"
 )
 
+// probeKey marks contexts of requests probing segments that may have expired.
+type probeKey struct{}
+
+// probeState is the state of a request probing a segment, shared by its
+// retries.
+type probeState struct {
+	// refreshed tells whether base URLs were refreshed for the request.
+	refreshed bool
+}
+
+// withProbe returns the context for a request probing a segment.
+func withProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, probeKey{}, &probeState{})
+}
+
+// probeOf returns the probe state of a request context, or nil if the request
+// is not a probe.
+func probeOf(ctx context.Context) *probeState {
+	probe, _ := ctx.Value(probeKey{}).(*probeState)
+	return probe
+}
+
 func NewClient(pb Playbacker) *retryablehttp.Client {
 	client := retryablehttp.NewClient()
 
@@ -34,7 +56,11 @@ func NewClient(pb Playbacker) *retryablehttp.Client {
 		return wait
 	}
 
-	client.CheckRetry = func(_ context.Context, resp *http.Response, err error) (bool, error) {
+	client.CheckRetry = func(
+		ctx context.Context,
+		resp *http.Response,
+		err error,
+	) (bool, error) {
 		if err != nil {
 			slog.Warn("got connection error, retrying", "error", err)
 			return true, err
@@ -44,6 +70,16 @@ func NewClient(pb Playbacker) *retryablehttp.Client {
 			return false, errors.New("got nil response")
 		}
 
+		// Expired segments are forbidden, but so are segments requested with
+		// expired base URLs. Probes refresh them once and take a segment
+		// still forbidden after that for unavailable.
+		if probe := probeOf(ctx); probe != nil && resp.StatusCode == http.StatusForbidden {
+			if probe.refreshed {
+				return false, nil
+			}
+			probe.refreshed = true
+		}
+
 		switch resp.StatusCode {
 		case http.StatusForbidden, http.StatusServiceUnavailable, http.StatusBadRequest:
 			slog.Warn(
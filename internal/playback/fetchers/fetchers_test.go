@@ -0,0 +1,147 @@
+package fetchers_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/playback/fetchers"
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "info.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestFileFetcher_FetchInfo_Dump(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, `{
+		"title": "Test title",
+		"channel": "Test channel",
+		"formats": [
+			{
+				"format_id": "140",
+				"fragment_base_url": "https://test/itag/140/mime/audio%2Fmp4/dur/2.000/",
+				"acodec": "mp4a.40.2",
+				"vcodec": "none",
+				"asr": 44100,
+				"tbr": 128.5
+			},
+			{
+				"format_id": "137",
+				"fragment_base_url": "https://test/itag/137/mime/video%2Fmp4/dur/2.000/",
+				"acodec": "none",
+				"vcodec": "avc1.640028",
+				"width": 1920,
+				"height": 1080,
+				"fps": 30
+			}
+		]
+	}`)
+
+	fetcher := &fetchers.FileFetcher{VideoID: testutil.TestVideoID, Path: path}
+	got, _, err := fetcher.FetchInfo(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "Test title", got.Title)
+	assert.Equal(t, 2*time.Second, got.SegmentDuration)
+	require.Len(t, got.AudioStreams, 1)
+	assert.Equal(t, 128_500, got.AudioStreams[0].Bitrate)
+	require.Len(t, got.VideoStreams, 1)
+	assert.Equal(t, 1080, got.VideoStreams[0].Height)
+}
+
+func TestFileFetcher_FetchInfo_Description(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, `{
+		"title": "Test title",
+		"timezone": "Asia/Tokyo",
+		"segmentDuration": "5s",
+		"streams": [
+			{"baseUrl": "https://test/itag/140/mime/audio%2Fmp4/"},
+			{"itag": "999", "baseUrl": "https://test/a", "mimeType": "video/mp4", "height": 480}
+		]
+	}`)
+
+	fetcher := &fetchers.FileFetcher{VideoID: testutil.TestVideoID, Path: path}
+	got, _, err := fetcher.FetchInfo(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "Asia/Tokyo", got.Timezone)
+	assert.Equal(t, 5*time.Second, got.SegmentDuration)
+	assert.Equal(t, []info.AudioStream{
+		{
+			CommonStream: info.CommonStream{
+				BaseURL:  "https://test/itag/140/mime/audio%2Fmp4/",
+				Codecs:   "mp4a.40.2",
+				Itag:     "140",
+				MimeType: "audio/mp4",
+				Bitrate:  128_000,
+			},
+			AudioSamplingRate: 44100,
+		},
+	}, got.AudioStreams)
+	assert.Equal(t, []info.VideoStream{
+		{
+			CommonStream: info.CommonStream{
+				BaseURL:  "https://test/a",
+				Itag:     "999",
+				MimeType: "video/mp4",
+			},
+			Height: 480,
+		},
+	}, got.VideoStreams)
+}
+
+func TestFileFetcher_FetchInfo_NoVideo(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, `{
+		"streams": [{"baseUrl": "https://test/itag/140/mime/audio%2Fmp4/dur/2.000/"}]
+	}`)
+
+	fetcher := &fetchers.FileFetcher{VideoID: testutil.TestVideoID, Path: path}
+	_, _, err := fetcher.FetchInfo(context.Background())
+	assert.ErrorContains(t, err, "no video streams")
+}
+
+func TestStaticURLFetcher(t *testing.T) {
+	t.Parallel()
+	fetcher, err := fetchers.New(fetchers.StaticURLName, fetchers.Options{
+		VideoID: testutil.TestVideoID,
+		Source: []string{
+			testutil.TestBaseURLs["136"],
+			testutil.TestBaseURLs["140"],
+		},
+	})
+	require.NoError(t, err)
+
+	got, _, err := fetcher.FetchInfo(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, testutil.TestVideoID, got.ID)
+	assert.Equal(t, 2*time.Second, got.SegmentDuration)
+	require.Len(t, got.VideoStreams, 1)
+	assert.Equal(t, "avc1.4d401f", got.VideoStreams[0].Codecs)
+	assert.Equal(t, 720, got.VideoStreams[0].Height)
+
+	baseURLs, err := fetcher.FetchBaseURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"136": testutil.TestBaseURLs["136"],
+		"140": testutil.TestBaseURLs["140"],
+	}, baseURLs)
+}
+
+func TestNew_Unknown(t *testing.T) {
+	t.Parallel()
+	_, err := fetchers.New("unknown", fetchers.Options{})
+	assert.ErrorContains(t, err, `unknown fetcher: "unknown"`)
+}
@@ -0,0 +1,225 @@
+package fetchers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/urlutil"
+)
+
+// FileFetcher loads video info from a file: either a yt-dlp info JSON (e.g.,
+// saved with --write-info-json) or a stream description. The file is read
+// again on each fetch, so base URLs can be refreshed by updating it.
+//
+// A stream description is a JSON object with streams given explicitly:
+//
+//	{
+//	  "title": "Stream title",
+//	  "segmentDuration": "2s",
+//	  "streams": [
+//	    {"itag": "140", "baseUrl": "https://...", "codecs": "mp4a.40.2"},
+//	    {"itag": "137", "baseUrl": "https://...", "width": 1920, "height": 1080}
+//	  ]
+//	}
+//
+// Missing mime types and segment duration are taken from base URLs, and
+// missing stream properties from known itags.
+type FileFetcher struct {
+	VideoID string
+	Path    string
+}
+
+type description struct {
+	Title           string              `json:"title"`
+	ChannelID       string              `json:"channelId"`
+	ChannelTitle    string              `json:"channelTitle"`
+	ActualStartTime time.Time           `json:"actualStartTime"`
+	Timezone        string              `json:"timezone"`
+	SegmentDuration string              `json:"segmentDuration"`
+	Streams         []streamDescription `json:"streams"`
+}
+
+type streamDescription struct {
+	Itag              string `json:"itag"`
+	BaseURL           string `json:"baseUrl"`
+	MimeType          string `json:"mimeType"`
+	Codecs            string `json:"codecs"`
+	Bitrate           int    `json:"bitrate"`
+	AudioSamplingRate int    `json:"audioSamplingRate"`
+	Width             int    `json:"width"`
+	Height            int    `json:"height"`
+	FrameRate         int    `json:"frameRate"`
+}
+
+func (fetcher *FileFetcher) FetchInfo(
+	_ context.Context,
+) (*info.VideoInformation, Additionals, error) {
+	content, isDump, err := fetcher.read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isDump {
+		return parseDump(fetcher.VideoID, content)
+	}
+
+	var d description
+	if err := json.Unmarshal(content, &d); err != nil {
+		return nil, nil, fmt.Errorf("parsing stream description: %w", err)
+	}
+	information, err := d.videoInfo(fetcher.VideoID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return information, nil, nil
+}
+
+func (fetcher *FileFetcher) FetchBaseURLs(ctx context.Context) (map[string]string, error) {
+	information, _, err := fetcher.FetchInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collectBaseURLs(information), nil
+}
+
+// read reads the file and reports whether it is a yt-dlp info JSON.
+func (fetcher *FileFetcher) read() ([]byte, bool, error) {
+	content, err := os.ReadFile(fetcher.Path)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading info file: %w", err)
+	}
+
+	var probe struct {
+		Formats json.RawMessage `json:"formats"`
+	}
+	if err := json.Unmarshal(content, &probe); err != nil {
+		return nil, false, fmt.Errorf("parsing info file: %w", err)
+	}
+
+	return content, probe.Formats != nil, nil
+}
+
+// videoInfo builds video info from a description, filling missing properties.
+func (d *description) videoInfo(videoID string) (*info.VideoInformation, error) {
+	information := &info.VideoInformation{
+		ID:              videoID,
+		Title:           d.Title,
+		ChannelID:       d.ChannelID,
+		ChannelTitle:    d.ChannelTitle,
+		ActualStartTime: d.ActualStartTime,
+		Timezone:        d.Timezone,
+		AudioStreams:    []info.AudioStream{},
+		VideoStreams:    []info.VideoStream{},
+	}
+
+	for _, s := range d.Streams {
+		if err := s.fill(); err != nil {
+			return nil, fmt.Errorf("bad stream %q: %w", s.Itag, err)
+		}
+		common := info.CommonStream{
+			BaseURL:  s.BaseURL,
+			Codecs:   s.Codecs,
+			Itag:     s.Itag,
+			MimeType: s.MimeType,
+			Bitrate:  s.Bitrate,
+		}
+		if strings.HasPrefix(s.MimeType, "audio/") {
+			information.AudioStreams = append(information.AudioStreams, info.AudioStream{
+				CommonStream:      common,
+				AudioSamplingRate: s.AudioSamplingRate,
+			})
+		} else {
+			information.VideoStreams = append(information.VideoStreams, info.VideoStream{
+				CommonStream: common,
+				Width:        s.Width,
+				Height:       s.Height,
+				FrameRate:    s.FrameRate,
+			})
+		}
+	}
+
+	if len(information.VideoStreams) == 0 {
+		return nil, errors.New("no video streams described")
+	}
+
+	if d.SegmentDuration != "" {
+		duration, err := time.ParseDuration(d.SegmentDuration)
+		if err != nil {
+			return nil, fmt.Errorf("parsing segment duration: %w", err)
+		}
+		information.SegmentDuration = duration
+	} else {
+		duration, err := parseSegmentDuration(information.VideoStreams[0].BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		information.SegmentDuration = duration
+	}
+
+	return information, nil
+}
+
+// fill fills missing properties of a stream from its base URL and known itags.
+func (s *streamDescription) fill() error {
+	if s.BaseURL == "" {
+		return errors.New("missing base URL")
+	}
+	if s.Itag == "" {
+		s.Itag = urlutil.ExtractParameter(s.BaseURL, "itag")
+		if s.Itag == "" {
+			return errors.New("missing itag")
+		}
+	}
+	if s.MimeType == "" {
+		raw := urlutil.ExtractParameter(s.BaseURL, "mime")
+		if raw == "" {
+			return fmt.Errorf("missing mime type parameter in base URL: %s", s.BaseURL)
+		}
+		mimeType, err := url.PathUnescape(raw)
+		if err != nil {
+			return fmt.Errorf("unescaping mime type: %w", err)
+		}
+		s.MimeType = mimeType
+	}
+
+	known, ok := knownItags[s.Itag]
+	if !ok {
+		return nil
+	}
+	if s.Codecs == "" {
+		s.Codecs = known.Codecs
+	}
+	if s.Bitrate == 0 {
+		s.Bitrate = known.Bitrate
+	}
+	if s.AudioSamplingRate == 0 {
+		s.AudioSamplingRate = known.AudioSamplingRate
+	}
+	if s.Width == 0 && s.Height == 0 {
+		s.Width, s.Height = known.Width, known.Height
+	}
+	if s.FrameRate == 0 {
+		s.FrameRate = known.FrameRate
+	}
+
+	return nil
+}
+
+func collectBaseURLs(information *info.VideoInformation) map[string]string {
+	baseURLs := make(map[string]string)
+	for _, s := range information.AudioStreams {
+		baseURLs[s.Itag] = s.BaseURL
+	}
+	for _, s := range information.VideoStreams {
+		baseURLs[s.Itag] = s.BaseURL
+	}
+	return baseURLs
+}
@@ -0,0 +1,103 @@
+package fetchers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/xymaxim/ypb/internal/exec"
+)
+
+// Names of built-in fetchers.
+const (
+	YtdlpName     = "yt-dlp"
+	FileName      = "file"
+	StaticURLName = "static"
+)
+
+// Options are passed to fetcher constructors. Fetchers ignore options they do
+// not need.
+type Options struct {
+	VideoID string
+	// Source is a fetcher-specific source, e.g., a file path for the file
+	// fetcher or base URLs for the static one.
+	Source []string
+	// Runner runs yt-dlp.
+	Runner  exec.Runner
+	OnPrint func([]byte)
+}
+
+// Constructor creates a fetcher from options.
+type Constructor func(opts Options) (Fetcher, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Constructor)
+)
+
+func init() {
+	Register(YtdlpName, func(opts Options) (Fetcher, error) {
+		return &YtdlpFetcher{
+			VideoID: opts.VideoID,
+			Runner:  opts.Runner,
+			OnPrint: opts.OnPrint,
+		}, nil
+	})
+	Register(FileName, func(opts Options) (Fetcher, error) {
+		if len(opts.Source) != 1 {
+			return nil, fmt.Errorf("expected one file, got %d", len(opts.Source))
+		}
+		return &FileFetcher{VideoID: opts.VideoID, Path: opts.Source[0]}, nil
+	})
+	Register(StaticURLName, func(opts Options) (Fetcher, error) {
+		if len(opts.Source) == 0 {
+			return nil, errors.New("no base URLs given")
+		}
+		return &StaticURLFetcher{VideoID: opts.VideoID, BaseURLs: opts.Source}, nil
+	})
+}
+
+// Register makes a fetcher available by name. It panics if the name is
+// already registered.
+func Register(name string, constructor Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic("fetchers: fetcher registered twice: " + name)
+	}
+	registry[name] = constructor
+}
+
+// New creates a fetcher registered by name.
+func New(name string, opts Options) (Fetcher, error) {
+	registryMu.RLock()
+	constructor, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown fetcher: %q", name)
+	}
+
+	fetcher, err := constructor(opts)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s fetcher: %w", name, err)
+	}
+
+	return fetcher, nil
+}
+
+// Names returns the sorted names of registered fetchers.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,63 @@
+package fetchers
+
+import (
+	"context"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+)
+
+// StaticURLFetcher builds video info from base URLs given directly. Stream
+// properties are taken from base URL parameters and known itags. Base URLs
+// are never refreshed, so playback lasts until they expire.
+type StaticURLFetcher struct {
+	VideoID  string
+	BaseURLs []string
+}
+
+func (fetcher *StaticURLFetcher) FetchInfo(
+	_ context.Context,
+) (*info.VideoInformation, Additionals, error) {
+	d := description{Title: fetcher.VideoID}
+	for _, baseURL := range fetcher.BaseURLs {
+		d.Streams = append(d.Streams, streamDescription{BaseURL: baseURL})
+	}
+
+	information, err := d.videoInfo(fetcher.VideoID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return information, nil, nil
+}
+
+func (fetcher *StaticURLFetcher) FetchBaseURLs(ctx context.Context) (map[string]string, error) {
+	information, _, err := fetcher.FetchInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collectBaseURLs(information), nil
+}
+
+// knownItag holds properties of an itag of YouTube live streams.
+type knownItag struct {
+	Codecs            string
+	Bitrate           int
+	AudioSamplingRate int
+	Width             int
+	Height            int
+	FrameRate         int
+}
+
+var knownItags = map[string]knownItag{
+	"139": {Codecs: "mp4a.40.5", Bitrate: 48_000, AudioSamplingRate: 22050},
+	"140": {Codecs: "mp4a.40.2", Bitrate: 128_000, AudioSamplingRate: 44100},
+	"141": {Codecs: "mp4a.40.2", Bitrate: 256_000, AudioSamplingRate: 44100},
+	"160": {Codecs: "avc1.4d400c", Width: 256, Height: 144, FrameRate: 30},
+	"133": {Codecs: "avc1.4d4015", Width: 426, Height: 240, FrameRate: 30},
+	"134": {Codecs: "avc1.4d401e", Width: 640, Height: 360, FrameRate: 30},
+	"135": {Codecs: "avc1.4d401f", Width: 854, Height: 480, FrameRate: 30},
+	"136": {Codecs: "avc1.4d401f", Width: 1280, Height: 720, FrameRate: 30},
+	"137": {Codecs: "avc1.640028", Width: 1920, Height: 1080, FrameRate: 30},
+	"298": {Codecs: "avc1.4d4020", Width: 1280, Height: 720, FrameRate: 60},
+	"299": {Codecs: "avc1.64002a", Width: 1920, Height: 1080, FrameRate: 60},
+}
@@ -3,6 +3,7 @@ package fetchers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -20,6 +21,11 @@ type YtdlpFetcher struct {
 	OnPrint func([]byte)
 }
 
+// printer is implemented by runners printing command output by default.
+type printer interface {
+	PrintCallback(b []byte)
+}
+
 type YtdlpAdditionals struct {
 	UserAgent string
 }
@@ -41,6 +47,7 @@ type format struct {
 	Width             *int              `json:"width"`
 	Height            *int              `json:"height"`
 	FrameRate         *int              `json:"fps"`
+	TotalBitrate      *float64          `json:"tbr"`
 	HTTPHeaders       map[string]string `json:"http_headers"`
 }
 
@@ -51,9 +58,21 @@ func (fetcher *YtdlpFetcher) FetchInfo(
 	if err != nil {
 		return nil, nil, fmt.Errorf("dumping video info: %w", err)
 	}
+	return parseDump(fetcher.VideoID, out)
+}
+
+func (fetcher *YtdlpFetcher) FetchBaseURLs(ctx context.Context) (map[string]string, error) {
+	out, err := fetcher.runDumpJSON(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dumping video info: %w", err)
+	}
+	return parseDumpBaseURLs(out)
+}
 
+// parseDump parses video info from a yt-dlp info JSON.
+func parseDump(videoID string, content []byte) (*info.VideoInformation, Additionals, error) {
 	var dump jsonDump
-	if err := json.Unmarshal([]byte(out), &dump); err != nil {
+	if err := json.Unmarshal(content, &dump); err != nil {
 		return nil, nil, fmt.Errorf("parsing info dump: %w", err)
 	}
 
@@ -76,13 +95,16 @@ func (fetcher *YtdlpFetcher) FetchInfo(
 			Itag:     f.FormatID,
 			MimeType: mimeType,
 		}
+		if f.TotalBitrate != nil {
+			common.Bitrate = int(*f.TotalBitrate * 1000)
+		}
 		if f.VideoCodec == "none" {
 			common.Codecs = f.AudioCodec
 			audioStreams = append(
 				audioStreams,
 				info.AudioStream{
 					CommonStream:      common,
-					AudioSamplingRate: *f.AudioSamplingRate,
+					AudioSamplingRate: valueOrZero(f.AudioSamplingRate),
 				},
 			)
 		} else {
@@ -91,27 +113,25 @@ func (fetcher *YtdlpFetcher) FetchInfo(
 				videoStreams,
 				info.VideoStream{
 					CommonStream: common,
-					Width:        *f.Width,
-					Height:       *f.Height,
-					FrameRate:    *f.FrameRate,
+					Width:        valueOrZero(f.Width),
+					Height:       valueOrZero(f.Height),
+					FrameRate:    valueOrZero(f.FrameRate),
 				},
 			)
 		}
 	}
 
-	someBaseURL := videoStreams[0].BaseURL
-	segmentDurationRaw := urlutil.ExtractParameter(someBaseURL, "dur")
-	if segmentDurationRaw == "" {
-		return nil, nil, fmt.Errorf("no 'dur' parameter in base URL: %s", someBaseURL)
+	if len(videoStreams) == 0 {
+		return nil, nil, errors.New("no video streams in info dump")
 	}
-	segmentDurationNumber, err := strconv.ParseFloat(segmentDurationRaw, 64)
+
+	segmentDuration, err := parseSegmentDuration(videoStreams[0].BaseURL)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parsing segment duration: %w", err)
+		return nil, nil, err
 	}
-	segmentDuration := time.Duration(segmentDurationNumber * float64(time.Second))
 
 	information := &info.VideoInformation{
-		ID:              fetcher.VideoID,
+		ID:              videoID,
 		Title:           dump.Title,
 		ChannelID:       dump.ChannelID,
 		ChannelTitle:    dump.ChannelTitle,
@@ -127,19 +147,15 @@ func (fetcher *YtdlpFetcher) FetchInfo(
 	return information, additionals, nil
 }
 
-func (fetcher *YtdlpFetcher) FetchBaseURLs(ctx context.Context) (map[string]string, error) {
-	out, err := fetcher.runDumpJSON(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("dumping video info: %w", err)
-	}
-
+// parseDumpBaseURLs parses base URLs, keyed by itags, from a yt-dlp info JSON.
+func parseDumpBaseURLs(content []byte) (map[string]string, error) {
 	var dump struct {
 		Formats []struct {
 			FormatID        string `json:"format_id"`
 			FragmentBaseURL string `json:"fragment_base_url"`
 		} `json:"formats"`
 	}
-	if err := json.Unmarshal([]byte(out), &dump); err != nil {
+	if err := json.Unmarshal(content, &dump); err != nil {
 		return nil, fmt.Errorf("parsing info dump: %w", err)
 	}
 
@@ -151,9 +167,35 @@ func (fetcher *YtdlpFetcher) FetchBaseURLs(ctx context.Context) (map[string]stri
 	return baseURLs, nil
 }
 
-func (fetcher *YtdlpFetcher) runDumpJSON(ctx context.Context) (string, error) {
+// parseSegmentDuration parses the segment duration from the 'dur' parameter
+// of a base URL.
+func parseSegmentDuration(baseURL string) (time.Duration, error) {
+	raw := urlutil.ExtractParameter(baseURL, "dur")
+	if raw == "" {
+		return 0, fmt.Errorf("no 'dur' parameter in base URL: %s", baseURL)
+	}
+	seconds, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing segment duration: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func valueOrZero[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func (fetcher *YtdlpFetcher) runDumpJSON(ctx context.Context) ([]byte, error) {
+	// Keep the default output of runners able to print it
+	runnerPrinter, _ := fetcher.Runner.(printer)
 	printCallback := func(chunk []byte) {
-		fetcher.Runner.(*exec.CommandRunner).PrintCallback(chunk)
+		if runnerPrinter != nil {
+			runnerPrinter.PrintCallback(chunk)
+		}
 		if fetcher.OnPrint != nil {
 			fetcher.OnPrint(chunk)
 		}
@@ -161,7 +203,7 @@ func (fetcher *YtdlpFetcher) runDumpJSON(ctx context.Context) (string, error) {
 
 	tempFile, err := os.CreateTemp("", "ypb-*")
 	if err != nil {
-		return "", fmt.Errorf("creating temp file: %w", err)
+		return nil, fmt.Errorf("creating temp file: %w", err)
 	}
 	defer tempFile.Close()
 
@@ -182,13 +224,13 @@ func (fetcher *YtdlpFetcher) runDumpJSON(ctx context.Context) (string, error) {
 		fetcher.VideoID,
 	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	content, err := os.ReadFile(jsonPath)
 	if err != nil {
-		return "", fmt.Errorf("reading output file: %w", err)
+		return nil, fmt.Errorf("reading output file: %w", err)
 	}
 
-	return string(content), nil
+	return content, nil
 }
@@ -0,0 +1,103 @@
+package info
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Stream selections of a filter.
+const (
+	SelectAll  = "all"
+	SelectBest = "best"
+	SelectNone = "none"
+)
+
+// StreamFilter restricts streams of video information. The zero value keeps
+// all streams.
+type StreamFilter struct {
+	// Itags, if not empty, are the only itags to keep.
+	Itags []string `json:"itags,omitempty"`
+	// MaxHeight, if positive, drops taller video streams.
+	MaxHeight int `json:"maxHeight,omitempty"`
+	// Video and Audio select streams of each type among the remaining ones:
+	// all of them (the default), the best one, or none.
+	Video string `json:"video,omitempty"`
+	Audio string `json:"audio,omitempty"`
+}
+
+// IsZero reports whether the filter keeps all streams.
+func (f StreamFilter) IsZero() bool {
+	return len(f.Itags) == 0 &&
+		f.MaxHeight <= 0 &&
+		(f.Video == "" || f.Video == SelectAll) &&
+		(f.Audio == "" || f.Audio == SelectAll)
+}
+
+// Validate checks the filter values.
+func (f StreamFilter) Validate() error {
+	for _, selection := range []string{f.Video, f.Audio} {
+		switch selection {
+		case "", SelectAll, SelectBest, SelectNone:
+		default:
+			return fmt.Errorf(
+				"bad stream selection %q, expected %s, %s, or %s",
+				selection, SelectAll, SelectBest, SelectNone,
+			)
+		}
+	}
+	if f.MaxHeight < 0 {
+		return fmt.Errorf("negative max height: %d", f.MaxHeight)
+	}
+	return nil
+}
+
+// Apply returns a copy of the information with only the streams matching the
+// filter. It fails if no streams are left.
+func (f StreamFilter) Apply(i VideoInformation) (VideoInformation, error) {
+	if err := f.Validate(); err != nil {
+		return VideoInformation{}, err
+	}
+
+	filtered := i
+	filtered.AudioStreams = []AudioStream{}
+	filtered.VideoStreams = []VideoStream{}
+
+	for _, s := range i.AudioStreams {
+		if f.keepsItag(s.Itag) {
+			filtered.AudioStreams = append(filtered.AudioStreams, s)
+		}
+	}
+	for _, s := range i.VideoStreams {
+		if f.keepsItag(s.Itag) && (f.MaxHeight <= 0 || s.Height <= f.MaxHeight) {
+			filtered.VideoStreams = append(filtered.VideoStreams, s)
+		}
+	}
+
+	switch f.Video {
+	case SelectBest:
+		if best := filtered.BestVideo(); best != nil {
+			filtered.VideoStreams = []VideoStream{*best}
+		}
+	case SelectNone:
+		filtered.VideoStreams = []VideoStream{}
+	}
+	switch f.Audio {
+	case SelectBest:
+		if best := filtered.BestAudio(); best != nil {
+			filtered.AudioStreams = []AudioStream{*best}
+		}
+	case SelectNone:
+		filtered.AudioStreams = []AudioStream{}
+	}
+
+	if len(filtered.VideoStreams) == 0 && len(filtered.AudioStreams) == 0 {
+		return VideoInformation{}, errors.New("no streams match the filter")
+	}
+
+	return filtered, nil
+}
+
+func (f StreamFilter) keepsItag(itag string) bool {
+	return len(f.Itags) == 0 || slices.Contains(f.Itags, itag)
+}
@@ -0,0 +1,93 @@
+package info_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/playback/info"
+	"github.com/xymaxim/ypb/internal/testutil"
+)
+
+func itags(i info.VideoInformation) []string {
+	var out []string
+	for _, s := range i.VideoStreams {
+		out = append(out, s.Itag)
+	}
+	for _, s := range i.AudioStreams {
+		out = append(out, s.Itag)
+	}
+	return out
+}
+
+func TestStreamFilter_Apply(t *testing.T) {
+	t.Parallel()
+
+	videoInfo, _, err := (&testutil.MockFetcher{}).FetchInfo(context.Background())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		filter info.StreamFilter
+		want   []string
+	}{
+		{
+			name: "zero filter keeps all",
+			want: []string{"136", "137", "140"},
+		},
+		{
+			name:   "itags",
+			filter: info.StreamFilter{Itags: []string{"137", "140"}},
+			want:   []string{"137", "140"},
+		},
+		{
+			name:   "max height",
+			filter: info.StreamFilter{MaxHeight: 720},
+			want:   []string{"136", "140"},
+		},
+		{
+			name:   "best video",
+			filter: info.StreamFilter{Video: info.SelectBest},
+			want:   []string{"137", "140"},
+		},
+		{
+			name:   "best video within max height",
+			filter: info.StreamFilter{MaxHeight: 720, Video: info.SelectBest},
+			want:   []string{"136", "140"},
+		},
+		{
+			name:   "no audio",
+			filter: info.StreamFilter{Audio: info.SelectNone},
+			want:   []string{"136", "137"},
+		},
+		{
+			name:   "no video",
+			filter: info.StreamFilter{Video: info.SelectNone},
+			want:   []string{"140"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tc.filter.Apply(*videoInfo)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, itags(got))
+		})
+	}
+}
+
+func TestStreamFilter_Apply_Errors(t *testing.T) {
+	t.Parallel()
+
+	videoInfo, _, err := (&testutil.MockFetcher{}).FetchInfo(context.Background())
+	require.NoError(t, err)
+
+	_, err = info.StreamFilter{Itags: []string{"999"}}.Apply(*videoInfo)
+	require.ErrorContains(t, err, "no streams match")
+
+	_, err = info.StreamFilter{Video: "worst"}.Apply(*videoInfo)
+	require.ErrorContains(t, err, `bad stream selection "worst"`)
+}
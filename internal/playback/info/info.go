@@ -10,6 +10,8 @@ type VideoInformation struct {
 	ChannelID       string
 	ChannelTitle    string
 	ActualStartTime time.Time
+	// Timezone is the IANA name of the stream's local time zone, if known.
+	Timezone        string
 	SegmentDuration time.Duration
 	AudioStreams    []AudioStream
 	VideoStreams    []VideoStream
@@ -32,11 +34,30 @@ func (i VideoInformation) BestVideo() *VideoStream {
 	return &best
 }
 
+// BestAudio returns the audio stream with the highest bitrate, or the highest
+// sampling rate if bitrates are equal (e.g., unknown).
+func (i VideoInformation) BestAudio() *AudioStream {
+	if len(i.AudioStreams) == 0 {
+		return nil
+	}
+	best := i.AudioStreams[0]
+	for _, s := range i.AudioStreams[1:] {
+		if s.Bitrate > best.Bitrate {
+			best = s
+		} else if s.Bitrate == best.Bitrate && s.AudioSamplingRate > best.AudioSamplingRate {
+			best = s
+		}
+	}
+	return &best
+}
+
 type CommonStream struct {
 	BaseURL  string
 	Codecs   string
 	Itag     string
 	MimeType string
+	// Bitrate is the average bitrate in bits per second, or zero if unknown.
+	Bitrate int
 }
 
 type AudioStream struct {
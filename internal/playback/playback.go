@@ -9,8 +9,10 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/xymaxim/ypb/internal/playback/cache"
 	"github.com/xymaxim/ypb/internal/playback/fetchers"
 	"github.com/xymaxim/ypb/internal/playback/info"
 	"github.com/xymaxim/ypb/internal/playback/segment"
@@ -19,6 +21,10 @@ import (
 
 type SequenceNumber = int
 
+// ErrSegmentUnavailable is returned for segments that are not available, such
+// as ones that expired out of the stream's rewind window.
+var ErrSegmentUnavailable = errors.New("segment unavailable")
+
 // SegmentMetadataFetchError wraps errors that occur when fetching segment metadata.
 type SegmentMetadataFetchError struct {
 	SequenceNumber SequenceNumber
@@ -39,9 +45,15 @@ func (e *SegmentMetadataFetchError) Unwrap() error {
 
 type Playbacker interface {
 	BaseURLs() map[string]string
-	FetchSegmentMetadata(itag string, sq SequenceNumber) (*segment.Metadata, error)
+	FetchSegmentMetadata(
+		itag string,
+		sq SequenceNumber,
+		options ...FetchOption,
+	) (*segment.Metadata, error)
+	FindGaps(start, end SequenceNumber) ([]Gap, error)
 	Info() info.VideoInformation
 	LocateMoment(time.Time, segment.Metadata, bool) (*RewindMoment, error)
+	NearestReference(t time.Time, fallback segment.Metadata) segment.Metadata
 	ProbeItag() string
 	RefreshBaseURLs() error
 	RequestHeadSeqNum() (int, error)
@@ -50,18 +62,75 @@ type Playbacker interface {
 
 var _ Playbacker = (*Playback)(nil)
 
+// Playback streams segments and locates moments of a live stream. It is safe
+// for concurrent use: base URLs are swapped atomically on refresh, and caches
+// and indexes are guarded by their own locks.
 type Playback struct {
-	baseURLs map[string]string
-	client   *http.Client
-	fetcher  fetchers.Fetcher
-	info     info.VideoInformation
+	// baseURLs is replaced as a whole on refresh, so readers never see a
+	// partially updated map.
+	baseURLs      atomic.Pointer[map[string]string]
+	refresh       refreshGroup
+	client        *http.Client
+	fetcher       fetchers.Fetcher
+	info          info.VideoInformation
+	metadataCache cache.Cache
+	index         timeIndex
+	stats         locateCounters
+	prefetch      *prefetcher
+	// knownHead is the last head sequence number seen in responses, or zero.
+	knownHead atomic.Int64
+}
+
+// LocateStats holds counters of locate operations and metadata requests
+// performed by Playback.
+type LocateStats struct {
+	Locates          int64
+	MetadataRequests int64
+}
+
+// RequestsPerLocate returns the average number of metadata requests per
+// located moment.
+func (s LocateStats) RequestsPerLocate() float64 {
+	if s.Locates == 0 {
+		return 0
+	}
+	return float64(s.MetadataRequests) / float64(s.Locates)
+}
+
+type locateCounters struct {
+	locates          atomic.Int64
+	metadataRequests atomic.Int64
 }
 
+// Option is a functional option for configuring Playback.
+type Option func(*Playback)
+
+// WithMetadataCache sets a cache used to look up segment metadata before
+// requesting it.
+func WithMetadataCache(c cache.Cache) Option {
+	return func(pb *Playback) {
+		pb.metadataCache = c
+	}
+}
+
+// WithPrefetch enables prefetching of the next window segments after each
+// streamed one. Zero disables prefetching.
+func WithPrefetch(window int) Option {
+	return func(pb *Playback) {
+		if window > 0 {
+			pb.prefetch = newPrefetcher(window)
+		}
+	}
+}
+
+// NewPlayback starts a playback of a stream. If base URLs expire, they are
+// refreshed in the background shortly before that until ctx is done.
 func NewPlayback(
 	ctx context.Context,
 	videoID string,
 	fetcher fetchers.Fetcher,
 	client *http.Client,
+	options ...Option,
 ) (*Playback, error) {
 	information, _, err := fetcher.FetchInfo(ctx)
 	if err != nil {
@@ -76,9 +145,13 @@ func NewPlayback(
 	}
 
 	pb := &Playback{
-		baseURLs: baseURLs,
-		fetcher:  fetcher,
-		info:     *information,
+		fetcher: fetcher,
+		info:    *information,
+	}
+	pb.baseURLs.Store(&baseURLs)
+
+	for _, o := range options {
+		o(pb)
 	}
 
 	if client == nil {
@@ -86,27 +159,48 @@ func NewPlayback(
 	}
 	pb.client = client
 
+	go pb.refreshInBackground(ctx)
+
 	return pb, nil
 }
 
+// BaseURLs returns the current base URLs keyed by itags. The returned map
+// should not be modified.
 func (pb *Playback) BaseURLs() map[string]string {
-	return pb.baseURLs
+	baseURLs := pb.baseURLs.Load()
+	if baseURLs == nil {
+		return nil
+	}
+	return *baseURLs
+}
+
+// LocateStats returns a snapshot of the locate statistics. Metadata requests
+// served from the cache are not counted.
+func (pb *Playback) LocateStats() LocateStats {
+	return LocateStats{
+		Locates:          pb.stats.locates.Load(),
+		MetadataRequests: pb.stats.metadataRequests.Load(),
+	}
 }
 
 func (pb *Playback) Info() info.VideoInformation {
 	return pb.info
 }
 
+// RefreshBaseURLs fetches new base URLs. Concurrent calls share a single
+// refresh.
 func (pb *Playback) RefreshBaseURLs() error {
-	slog.Debug("refreshing base URLs")
-	baseURLs, err := pb.fetcher.FetchBaseURLs(context.Background())
-	if err != nil {
-		return fmt.Errorf("fetching base URLs: %w", err)
-	}
+	return pb.refresh.do(func() error {
+		slog.Debug("refreshing base URLs")
+		baseURLs, err := pb.fetcher.FetchBaseURLs(context.Background())
+		if err != nil {
+			return fmt.Errorf("fetching base URLs: %w", err)
+		}
 
-	pb.baseURLs = baseURLs
+		pb.baseURLs.Store(&baseURLs)
 
-	return nil
+		return nil
+	})
 }
 
 func (pb *Playback) RequestHeadSeqNum() (int, error) {
@@ -129,6 +223,7 @@ func (pb *Playback) RequestHeadSeqNum() (int, error) {
 	if err != nil {
 		return -1, fmt.Errorf("converting head sequence number: %w", err)
 	}
+	pb.knownHead.Store(int64(result))
 
 	return result, nil
 }
@@ -138,15 +233,62 @@ func (pb *Playback) ProbeItag() string {
 }
 
 func (pb *Playback) StreamSegment(itag string, sq SequenceNumber, w io.Writer) error {
-	return pb.streamSegmentPartial(itag, sq, 0, w)
+	if pb.prefetch != nil {
+		return pb.prefetch.stream(pb, itag, sq, w)
+	}
+	return pb.streamSegmentPartial(context.Background(), itag, sq, 0, w)
+}
+
+// FetchOption is a functional option for fetching segments.
+type FetchOption func(*fetchConfig)
+
+type fetchConfig struct {
+	probe bool
+}
+
+// AsProbe takes a segment still forbidden after refreshing base URLs once for
+// an unavailable one. It is used to probe segments that may have expired.
+func AsProbe() FetchOption {
+	return func(c *fetchConfig) {
+		c.probe = true
+	}
 }
 
 func (pb *Playback) FetchSegmentMetadata(
 	itag string,
 	sq SequenceNumber,
+	options ...FetchOption,
 ) (*segment.Metadata, error) {
+	var cfg fetchConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	ctx := context.Background()
+	if cfg.probe {
+		ctx = withProbe(ctx)
+	}
+
+	return pb.fetchSegmentMetadata(ctx, itag, sq)
+}
+
+func (pb *Playback) fetchSegmentMetadata(
+	ctx context.Context,
+	itag string,
+	sq SequenceNumber,
+) (*segment.Metadata, error) {
+	key := cache.Key{VideoID: pb.info.ID, Itag: itag, SequenceNumber: sq}
+	if pb.metadataCache != nil {
+		if sm, ok := pb.metadataCache.Get(key); ok {
+			pb.indexSegment(itag, sm)
+			return sm, nil
+		}
+	}
+
+	pb.stats.metadataRequests.Add(1)
+
 	var buf bytes.Buffer
-	err := pb.streamSegmentPartial(itag, sq, segment.MetadataLength, &buf)
+	err := pb.streamSegmentPartial(ctx, itag, sq, segment.MetadataLength, &buf)
 	if err != nil {
 		return nil, fmt.Errorf("downloading segment metadata, sq=%d: %w", sq, err)
 	}
@@ -156,10 +298,25 @@ func (pb *Playback) FetchSegmentMetadata(
 		return nil, fmt.Errorf("parsing metadata: %w", err)
 	}
 
+	if pb.metadataCache != nil {
+		if err := pb.metadataCache.Put(key, sm); err != nil {
+			slog.Warn("caching segment metadata", "sq", sq, "error", err)
+		}
+	}
+	pb.indexSegment(itag, sm)
+
 	return sm, nil
 }
 
+// indexSegment adds metadata of the probe itag to the time index.
+func (pb *Playback) indexSegment(itag string, sm *segment.Metadata) {
+	if itag == pb.ProbeItag() {
+		pb.index.add(*sm)
+	}
+}
+
 func (pb *Playback) streamSegmentPartial(
+	ctx context.Context,
 	itag string,
 	sq SequenceNumber,
 	length int64,
@@ -174,7 +331,7 @@ func (pb *Playback) streamSegmentPartial(
 		return fmt.Errorf("building segment URL: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return fmt.Errorf("creating new request: %w", err)
 	}
@@ -188,6 +345,10 @@ func (pb *Playback) streamSegmentPartial(
 	}
 	defer resp.Body.Close()
 
+	if head, err := strconv.Atoi(resp.Header.Get("X-Head-Seqnum")); err == nil {
+		pb.knownHead.Store(int64(head))
+	}
+
 	switch resp.StatusCode {
 	case http.StatusOK, http.StatusPartialContent:
 		reader := io.Reader(resp.Body)
@@ -196,6 +357,14 @@ func (pb *Playback) streamSegmentPartial(
 		}
 		_, err := io.Copy(w, reader)
 		return err
+	case http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: got status %s", ErrSegmentUnavailable, resp.Status)
+	case http.StatusForbidden:
+		// Forbidden responses only get here for probes, others are retried
+		if probeOf(ctx) != nil {
+			return fmt.Errorf("%w: got status %s", ErrSegmentUnavailable, resp.Status)
+		}
+		return fmt.Errorf("got unexpected status: %s", resp.Status)
 	default:
 		return fmt.Errorf("got unexpected status: %s", resp.Status)
 	}
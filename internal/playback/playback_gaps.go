@@ -0,0 +1,127 @@
+// This file extends Playback with detection of gaps in a stream timeline.
+//
+// A gap is a discontinuity between two adjacent segments, where the ingestion
+// walltime jumps by more than the segment duration (plus the threshold). Since
+// the timeline usually catches up after a gap (segments following it are
+// shorter), gaps cannot be reliably detected from the elapsed time of long
+// ranges, so every segment in a range is walked.
+
+package playback
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+// gapThreshold is the minimum excess of the walltime difference between
+// adjacent segments over the segment duration to consider it a gap. Segment
+// metadata only carries the target duration, while actual walltime differences
+// deviate from it by up to about 100 ms (see gap cases in testdata), so
+// timeDiffTolerance, used to match a segment to a time, would report jitter as
+// gaps. The threshold is shared by all gap-aware outputs through FindGaps.
+const gapThreshold = 500 * time.Millisecond
+
+// gapScanWorkers limits the number of concurrent metadata requests while
+// scanning for gaps.
+const gapScanWorkers = 8
+
+// Gap describes a discontinuity between two adjacent segments.
+type Gap struct {
+	Before segment.Metadata
+	After  segment.Metadata
+}
+
+// StartTime returns the end time of the last segment before the gap.
+func (g *Gap) StartTime() time.Time {
+	return g.Before.EndTime()
+}
+
+// EndTime returns the start time of the first segment after the gap.
+func (g *Gap) EndTime() time.Time {
+	return g.After.Time()
+}
+
+// Duration returns the duration of the gap.
+func (g *Gap) Duration() time.Duration {
+	return g.EndTime().Sub(g.StartTime())
+}
+
+// FindGaps returns all gaps between the start and end segments, inclusive, in
+// the timeline order.
+func (pb *Playback) FindGaps(start, end SequenceNumber) ([]Gap, error) {
+	if start > end {
+		return nil, fmt.Errorf("start segment is after end segment: %d > %d", start, end)
+	}
+
+	slog.Info("finding gaps", slog.Int("start", start), slog.Int("end", end))
+
+	timeline, err := pb.fetchTimeline(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	gaps := DetectGaps(timeline)
+
+	slog.Info("found gaps", slog.Int("count", len(gaps)))
+
+	return gaps, nil
+}
+
+// DetectGaps returns gaps between adjacent segments of the timeline, ordered
+// by sequence number.
+func DetectGaps(timeline []segment.Metadata) []Gap {
+	gaps := []Gap{}
+	for i := 1; i < len(timeline); i++ {
+		previous, current := timeline[i-1], timeline[i]
+		diff := current.Time().Sub(previous.Time())
+		if diff > previous.Duration+gapThreshold {
+			slog.Debug(
+				"gap found",
+				slog.Int("before", previous.SequenceNumber),
+				slog.Int("after", current.SequenceNumber),
+				slog.Duration("diff", diff),
+			)
+			gaps = append(gaps, Gap{Before: previous, After: current})
+		}
+	}
+	return gaps
+}
+
+// fetchTimeline fetches metadata of all segments between start and end,
+// inclusive, concurrently.
+func (pb *Playback) fetchTimeline(start, end SequenceNumber) ([]segment.Metadata, error) {
+	timeline := make([]segment.Metadata, end-start+1)
+	errs := make([]error, len(timeline))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(gapScanWorkers, len(timeline)) {
+		wg.Go(func() {
+			for i := range indexes {
+				metadata, err := fetchSegmentMetadata(pb, start+i)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				timeline[i] = *metadata
+			}
+		})
+	}
+	for i := range timeline {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return timeline, nil
+}
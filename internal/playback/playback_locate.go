@@ -83,8 +83,9 @@ func (i *RewindInterval) Duration() time.Duration {
 // LocateMoment finds the RewindMoment corresponding to a targetTime.
 //
 // The search begins from a reference point (typically the head segment or the
-// closest known segment to the target). If isEnd is true, the search moment is
-// treated as an interval end.
+// closest known segment to the target) and stays within the segments seen
+// around the target, e.g., by previous searches. If isEnd is true, the search
+// moment is treated as an interval end.
 func (pb *Playback) LocateMoment(
 	targetTime time.Time,
 	reference segment.Metadata,
@@ -101,6 +102,15 @@ func (pb *Playback) LocateMoment(
 		),
 	)
 
+	pb.stats.locates.Add(1)
+
+	// Known segments around the target, left by previous searches, bound the
+	// search domain
+	lower, upper := pb.index.neighbours(targetTime)
+	if lower != nil && !lower.Time().Before(targetTime) {
+		lower = nil
+	}
+
 	// Step 1: Jump-based search to quickly locate a segment or narrow the
 	// search domain for next steps.
 	var track []SequenceNumber
@@ -155,8 +165,12 @@ func (pb *Playback) LocateMoment(
 			break
 		}
 
-		// Jump to next candidate segment
-		currentSeqNum += calculateSegmentOffset(targetTime, candidate, isEnd)
+		// Jump to next candidate segment, not beyond the known bounds
+		currentSeqNum = clampToBounds(
+			currentSeqNum+calculateSegmentOffset(targetTime, candidate, isEnd),
+			lower,
+			upper,
+		)
 		candidate, err = fetchSegmentMetadata(pb, currentSeqNum)
 		if err != nil {
 			return nil, err
@@ -165,7 +179,12 @@ func (pb *Playback) LocateMoment(
 
 	// Step 2 and 3: Binary search within discovered domain and gap detection
 	var moment *RewindMoment
-	startSeqNum, endSeqNum := track[len(track)-2], track[len(track)-1]
+	// The domain bounds come in the search order, which depends on whether
+	// the start segment is before or after the target
+	startSeqNum := min(track[len(track)-2], track[len(track)-1])
+	endSeqNum := max(track[len(track)-2], track[len(track)-1])
+	startSeqNum, endSeqNum = clampToBounds(startSeqNum, lower, upper),
+		clampToBounds(endSeqNum, lower, upper)
 	moment, err = pb.searchInRange(targetTime, startSeqNum, endSeqNum, isEnd)
 	if err != nil {
 		return nil, fmt.Errorf("searching in range: %w", err)
@@ -181,6 +200,66 @@ func (pb *Playback) LocateMoment(
 	return moment, nil
 }
 
+// NearestReference returns the segment to start locating t from. A seen
+// segment containing t is returned as is. If t lies between two seen
+// segments, the reference is interpolated from them, which accounts for gaps
+// between them. Otherwise, the closest one in time to t is picked among the
+// fallback and the seen segments.
+func (pb *Playback) NearestReference(t time.Time, fallback segment.Metadata) segment.Metadata {
+	before, after := pb.index.neighbours(t)
+
+	if before != nil && containsTime(before, t) {
+		return *before
+	}
+
+	if before != nil && after != nil {
+		sq := interpolateSeqNum(t, *before, *after)
+		metadata, err := fetchSegmentMetadata(pb, sq)
+		if err == nil {
+			slog.Debug(
+				"interpolated reference segment",
+				slog.Int("sq", sq),
+				slog.Int("before", before.SequenceNumber),
+				slog.Int("after", after.SequenceNumber),
+			)
+			return *metadata
+		}
+		slog.Warn("fetching interpolated reference segment", "sq", sq, "error", err)
+	}
+
+	closest := fallback
+	for _, m := range []*segment.Metadata{before, after} {
+		if m != nil && timeDistance(t, m) < timeDistance(t, &closest) {
+			closest = *m
+		}
+	}
+
+	return closest
+}
+
+// clampToBounds limits sq to the known segments around the target, if any.
+func clampToBounds(sq SequenceNumber, lower, upper *segment.Metadata) SequenceNumber {
+	if lower != nil {
+		sq = max(sq, lower.SequenceNumber)
+	}
+	if upper != nil {
+		sq = min(sq, upper.SequenceNumber)
+	}
+	return sq
+}
+
+// containsTime reports whether t falls within the segment m.
+func containsTime(m *segment.Metadata, t time.Time) bool {
+	diff := t.Sub(m.Time())
+	return 0 <= diff && diff <= m.Duration+timeDiffTolerance
+}
+
+// timeDistance returns the absolute time difference between t and the segment
+// start.
+func timeDistance(t time.Time, m *segment.Metadata) time.Duration {
+	return t.Sub(m.Time()).Abs()
+}
+
 // searchInRange performs binary search within the specified domain and handles
 // gaps. This implements Step 2 and Step 3 of the search algorithm.
 func (pb *Playback) searchInRange(
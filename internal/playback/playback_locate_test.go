@@ -17,6 +17,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/cache"
 	"github.com/xymaxim/ypb/internal/playback/segment"
 	"github.com/xymaxim/ypb/internal/testutil"
 )
@@ -530,3 +531,119 @@ func TestPlayback_LocateMoment_GapCase3(t *testing.T) {
 		})
 	}
 }
+
+func TestPlayback_NearestReference_Interpolated(t *testing.T) {
+	t.Parallel()
+
+	// A timeline with a 10-minute gap after sq=5000
+	const gapAfter, gapDuration = 5000, 10 * time.Minute
+	metadataMapping := testutil.GenerateFakeSegmentMetadata(10000, 2*time.Second)
+	for sq := gapAfter + 1; sq < len(metadataMapping); sq++ {
+		m := metadataMapping[sq]
+		m.IngestionWalltime = m.IngestionWalltime.Add(gapDuration)
+		metadataMapping[sq] = m
+	}
+
+	ts := httptest.NewServer(
+		http.HandlerFunc(testutil.MakeSegmentMetadataHandler(t, metadataMapping)),
+	)
+	defer ts.Close()
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(ts.URL),
+		playback.WithMetadataCache(cache.NewMemoryCache(0)),
+	)
+	require.NoError(t, err)
+
+	head := metadataMapping[len(metadataMapping)-1]
+	locate := func(sq playback.SequenceNumber) int64 {
+		before := pb.LocateStats()
+		target := metadataMapping[sq].IngestionWalltime.Add(time.Second)
+		moment, err := pb.LocateMoment(target, pb.NearestReference(target, head), false)
+		require.NoError(t, err)
+		assert.Equal(t, sq, moment.Metadata.SequenceNumber)
+		return pb.LocateStats().MetadataRequests - before.MetadataRequests
+	}
+
+	first := locate(2000)
+	second := locate(2010)
+	assert.Less(t, second, first, "nearby locate should take fewer requests")
+	assert.Equal(t, int64(2), pb.LocateStats().Locates)
+
+	// A seen segment containing the target is the reference itself, even
+	// closer to the start of the next seen one
+	_, err = pb.FetchSegmentMetadata(pb.ProbeItag(), 2011)
+	require.NoError(t, err)
+	before := pb.LocateStats()
+	target := metadataMapping[2010].IngestionWalltime.Add(1900 * time.Millisecond)
+	reference := pb.NearestReference(target, head)
+	assert.Equal(t, 2010, reference.SequenceNumber)
+	assert.Equal(t, before.MetadataRequests, pb.LocateStats().MetadataRequests)
+}
+
+func TestPlayback_FindGaps(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		path     string
+		start    int
+		end      int
+		expected [][2]int
+	}{
+		{
+			name:     "gap case 2",
+			path:     "testdata/gap-case-2.csv",
+			start:    7947314,
+			end:      7947346,
+			expected: [][2]int{{7947334, 7947335}},
+		},
+		{
+			name:     "gap case 3",
+			path:     "testdata/gap-case-3.csv",
+			start:    7958090,
+			end:      7958122,
+			expected: [][2]int{{7958103, 7958104}},
+		},
+		{
+			name:     "no gaps",
+			path:     "testdata/gap-case-2.csv",
+			start:    7947335,
+			end:      7947346,
+			expected: [][2]int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gapCase := readGapCaseMetadata(t, tc.path)
+			ts := httptest.NewServer(http.HandlerFunc(makeGapCaseHandler(t, gapCase)))
+			defer ts.Close()
+
+			pb, err := playback.NewPlayback(
+				context.Background(),
+				testutil.TestVideoID,
+				&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+				testutil.NewClient(ts.URL),
+			)
+			require.NoError(t, err)
+
+			gaps, err := pb.FindGaps(tc.start, tc.end)
+			require.NoError(t, err)
+
+			actual := [][2]int{}
+			for _, g := range gaps {
+				actual = append(
+					actual,
+					[2]int{g.Before.SequenceNumber, g.After.SequenceNumber},
+				)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
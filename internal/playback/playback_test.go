@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,6 +17,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/xymaxim/ypb/internal/playback"
+	"github.com/xymaxim/ypb/internal/playback/cache"
+	"github.com/xymaxim/ypb/internal/playback/fetchers"
+	"github.com/xymaxim/ypb/internal/playback/info"
 	"github.com/xymaxim/ypb/internal/playback/segment"
 	"github.com/xymaxim/ypb/internal/testutil"
 )
@@ -42,6 +48,100 @@ func TestPlayback_RefreshBaseURLs(t *testing.T) {
 	)
 }
 
+// expiringFetcher serves base URLs with the 'expire' parameter and counts
+// refreshes. Refreshed base URLs expire in an hour.
+type expiringFetcher struct {
+	testutil.MockFetcher
+	expire    time.Time
+	delay     time.Duration
+	refreshes atomic.Int32
+}
+
+func withExpire(baseURL string, expire time.Time) string {
+	return fmt.Sprintf("%sexpire/%d/", baseURL, expire.Unix())
+}
+
+func (f *expiringFetcher) FetchInfo(
+	ctx context.Context,
+) (*info.VideoInformation, fetchers.Additionals, error) {
+	information, additionals, err := f.MockFetcher.FetchInfo(ctx)
+	for i, s := range information.AudioStreams {
+		information.AudioStreams[i].BaseURL = withExpire(s.BaseURL, f.expire)
+	}
+	for i, s := range information.VideoStreams {
+		information.VideoStreams[i].BaseURL = withExpire(s.BaseURL, f.expire)
+	}
+	return information, additionals, err
+}
+
+func (f *expiringFetcher) FetchBaseURLs(_ context.Context) (map[string]string, error) {
+	f.refreshes.Add(1)
+	time.Sleep(f.delay)
+	baseURLs := make(map[string]string)
+	for itag, baseURL := range testutil.TestBaseURLs {
+		baseURLs[itag] = withExpire(baseURL, time.Now().Add(time.Hour))
+	}
+	return baseURLs, nil
+}
+
+func TestPlayback_RefreshBaseURLs_SingleFlight(t *testing.T) {
+	t.Parallel()
+	fetcher := &expiringFetcher{expire: time.Now().Add(time.Hour), delay: 50 * time.Millisecond}
+	pb, err := playback.NewPlayback(context.Background(), testutil.TestVideoID, fetcher, nil)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			assert.NoError(t, pb.RefreshBaseURLs())
+			assert.Len(t, pb.BaseURLs(), 3)
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), fetcher.refreshes.Load())
+}
+
+func TestPlayback_RefreshesBeforeExpiry(t *testing.T) {
+	t.Parallel()
+	// Base URLs are refreshed five minutes before they expire
+	expire := time.Now().Add(5*time.Minute + 100*time.Millisecond)
+	fetcher := &expiringFetcher{expire: expire}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pb, err := playback.NewPlayback(ctx, testutil.TestVideoID, fetcher, nil)
+	require.NoError(t, err)
+
+	got, ok := playback.BaseURLsExpiry(pb.BaseURLs())
+	require.True(t, ok)
+	assert.Equal(t, expire.Unix(), got.Unix())
+
+	assert.Eventually(t, func() bool {
+		return fetcher.refreshes.Load() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	got, ok = playback.BaseURLsExpiry(pb.BaseURLs())
+	require.True(t, ok)
+	assert.True(t, got.After(expire))
+	assert.Equal(t, int32(1), fetcher.refreshes.Load(), "should not refresh again")
+}
+
+func TestBaseURLsExpiry(t *testing.T) {
+	t.Parallel()
+
+	_, ok := playback.BaseURLsExpiry(testutil.TestBaseURLs)
+	assert.False(t, ok)
+
+	got, ok := playback.BaseURLsExpiry(map[string]string{
+		"136": "https://test/itag/136/expire/2000/",
+		"140": "https://test/itag/140/expire/1000/",
+		"137": "https://test/itag/137/",
+	})
+	require.True(t, ok)
+	assert.Equal(t, time.Unix(1000, 0), got)
+}
+
 func TestPlayback_RequestHeadSeqNum_Success(t *testing.T) {
 	t.Parallel()
 
@@ -187,3 +287,157 @@ Target-Duration-Us: 2000000`
 		data,
 	)
 }
+
+func TestPlayback_FetchSegmentMetadata_Cached(t *testing.T) {
+	t.Parallel()
+
+	var requestCount int
+	metadata := testutil.GenerateFakeSegmentMetadata(1, 2*time.Second)
+	handler := testutil.MakeSegmentMetadataHandler(t, metadata)
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestCount++
+			handler(w, r)
+		}),
+	)
+	defer ts.Close()
+
+	fetcher := &testutil.MockFetcher{VideoID: testutil.TestVideoID}
+	pb, _ := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		fetcher,
+		testutil.NewClient(ts.URL),
+		playback.WithMetadataCache(cache.NewMemoryCache(0)),
+	)
+
+	for range 3 {
+		data, err := pb.FetchSegmentMetadata("140", 0)
+		require.NoError(t, err)
+		assert.Equal(t, metadata[0].IngestionWalltime, data.IngestionWalltime)
+	}
+	assert.Equal(t, 1, requestCount)
+}
+
+func TestPlayback_StreamSegment_Prefetch(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requested := map[string]int{}
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sq := path.Base(r.URL.Path)
+			mu.Lock()
+			requested[sq]++
+			mu.Unlock()
+			w.Header().Set("X-Head-Seqnum", "12")
+			fmt.Fprintf(w, "segment %s", sq)
+		}),
+	)
+	defer ts.Close()
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(ts.URL),
+		playback.WithPrefetch(3),
+	)
+	require.NoError(t, err)
+
+	for sq := 10; sq <= 12; sq++ {
+		var buf bytes.Buffer
+		require.NoError(t, pb.StreamSegment("137", sq, &buf))
+		assert.Equal(t, fmt.Sprintf("segment %d", sq), buf.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(
+		t,
+		map[string]int{"10": 1, "11": 1, "12": 1},
+		requested,
+		"each segment should be requested once, not beyond the head",
+	)
+}
+
+func TestPlayback_StreamSegment_PrefetchUnknownHead(t *testing.T) {
+	t.Parallel()
+
+	var requested atomic.Int32
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested.Add(1)
+			fmt.Fprintf(w, "segment %s", path.Base(r.URL.Path))
+		}),
+	)
+	defer ts.Close()
+
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		&testutil.MockFetcher{VideoID: testutil.TestVideoID},
+		testutil.NewClient(ts.URL),
+		playback.WithPrefetch(3),
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, pb.StreamSegment("137", 10, &buf))
+	assert.Never(
+		t,
+		func() bool { return requested.Load() > 1 },
+		100*time.Millisecond,
+		10*time.Millisecond,
+		"nothing should be prefetched until the head is known",
+	)
+}
+
+// refreshCountingPlayback counts refreshes of base URLs made by the client.
+type refreshCountingPlayback struct {
+	*playback.Playback
+	refreshes atomic.Int32
+}
+
+func (pb *refreshCountingPlayback) RefreshBaseURLs() error {
+	pb.refreshes.Add(1)
+	return pb.Playback.RefreshBaseURLs()
+}
+
+func TestPlayback_FetchSegmentMetadata_ProbeForbidden(t *testing.T) {
+	t.Parallel()
+
+	var requestCount atomic.Int32
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requestCount.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+		}),
+	)
+	defer ts.Close()
+
+	counting := &refreshCountingPlayback{}
+	client := playback.NewClient(counting)
+	client.HTTPClient = testutil.NewClient(ts.URL)
+	client.RetryWaitMax = time.Millisecond
+
+	fetcher := &testutil.MockFetcher{VideoID: testutil.TestVideoID}
+	pb, err := playback.NewPlayback(
+		context.Background(),
+		testutil.TestVideoID,
+		fetcher,
+		client.StandardClient(),
+	)
+	require.NoError(t, err)
+	counting.Playback = pb
+
+	_, err = pb.FetchSegmentMetadata("140", 123, playback.AsProbe())
+	require.ErrorIs(t, err, playback.ErrSegmentUnavailable)
+	assert.Equal(t, int32(2), requestCount.Load(), "probe should be retried once")
+	assert.Equal(t, int32(1), counting.refreshes.Load(), "probe should refresh base URLs once")
+
+	_, err = pb.FetchSegmentMetadata("140", 124)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, playback.ErrSegmentUnavailable)
+	assert.Positive(t, counting.refreshes.Load(), "fetch should refresh base URLs")
+}
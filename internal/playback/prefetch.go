@@ -0,0 +1,163 @@
+package playback
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// prefetchTTL is how long prefetched segments are kept.
+	prefetchTTL = 30 * time.Second
+	// prefetchWorkers is the maximum number of workers prefetching segments
+	// at once.
+	prefetchWorkers = 4
+)
+
+type prefetchKey struct {
+	itag string
+	sq   SequenceNumber
+}
+
+type prefetchEntry struct {
+	done    chan struct{}
+	data    []byte
+	err     error
+	expires time.Time
+}
+
+// prefetchTask is a scheduled segment to prefetch.
+type prefetchTask struct {
+	key   prefetchKey
+	entry *prefetchEntry
+}
+
+// prefetcher reads ahead segments following requested ones and keeps them in
+// a short-lived cache. Scheduled segments are queued and prefetched by a
+// bounded number of workers.
+type prefetcher struct {
+	window  int
+	mu      sync.Mutex
+	entries map[prefetchKey]*prefetchEntry
+	queue   []prefetchTask
+	// running is the number of running workers.
+	running int
+}
+
+func newPrefetcher(window int) *prefetcher {
+	return &prefetcher{
+		window:  window,
+		entries: make(map[prefetchKey]*prefetchEntry),
+	}
+}
+
+// stream writes a segment to w, from the cache if it has been prefetched (or
+// is being prefetched), and schedules prefetching of the next segments.
+func (p *prefetcher) stream(pb *Playback, itag string, sq SequenceNumber, w io.Writer) error {
+	key := prefetchKey{itag: itag, sq: sq}
+
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	p.mu.Unlock()
+
+	if ok {
+		<-entry.done
+		if entry.err == nil {
+			slog.Debug("serving prefetched segment", "itag", itag, "sq", sq)
+			if _, err := w.Write(entry.data); err != nil {
+				return fmt.Errorf("writing prefetched segment: %w", err)
+			}
+			p.schedule(pb, itag, sq+1)
+			return nil
+		}
+		// Prefetching failed, so request the segment again
+		p.mu.Lock()
+		delete(p.entries, key)
+		p.mu.Unlock()
+	}
+
+	if err := pb.streamSegmentPartial(context.Background(), itag, sq, 0, w); err != nil {
+		return err
+	}
+	p.schedule(pb, itag, sq+1)
+
+	return nil
+}
+
+// schedule queues prefetching of the window of segments starting from sq, not
+// exceeding the last known head segment. Nothing is prefetched until the head
+// is known, since segments past it would only fail.
+func (p *prefetcher) schedule(pb *Playback, itag string, from SequenceNumber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.evictExpired(time.Now())
+
+	head := int(pb.knownHead.Load())
+	if head == 0 {
+		return
+	}
+	for sq := from; sq < from+p.window && sq <= head; sq++ {
+		key := prefetchKey{itag: itag, sq: sq}
+		if _, ok := p.entries[key]; ok {
+			continue
+		}
+		entry := &prefetchEntry{done: make(chan struct{})}
+		p.entries[key] = entry
+		p.queue = append(p.queue, prefetchTask{key: key, entry: entry})
+	}
+
+	for p.running < prefetchWorkers && p.running < len(p.queue) {
+		p.running++
+		go p.work(pb)
+	}
+}
+
+// work prefetches queued segments until the queue is empty.
+func (p *prefetcher) work(pb *Playback) {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+		task := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		p.fetch(pb, task.key, task.entry)
+	}
+}
+
+func (p *prefetcher) fetch(pb *Playback, key prefetchKey, entry *prefetchEntry) {
+	var buf bytes.Buffer
+	err := pb.streamSegmentPartial(context.Background(), key.itag, key.sq, 0, &buf)
+	if err != nil {
+		slog.Debug("prefetching failed", "itag", key.itag, "sq", key.sq, "err", err)
+	}
+
+	p.mu.Lock()
+	entry.data, entry.err = buf.Bytes(), err
+	entry.expires = time.Now().Add(prefetchTTL)
+	close(entry.done)
+	p.mu.Unlock()
+}
+
+// evictExpired removes completed entries that have expired. It should be
+// called with the mutex held.
+func (p *prefetcher) evictExpired(now time.Time) {
+	for key, entry := range p.entries {
+		select {
+		case <-entry.done:
+			if now.After(entry.expires) {
+				delete(p.entries, key)
+			}
+		default:
+		}
+	}
+}
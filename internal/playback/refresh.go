@@ -0,0 +1,123 @@
+package playback
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/urlutil"
+)
+
+const (
+	// refreshMargin is how long before the expiry base URLs are refreshed.
+	refreshMargin = 5 * time.Minute
+	// refreshRetryInterval is how long to wait after a failed background
+	// refresh before trying again.
+	refreshRetryInterval = 30 * time.Second
+)
+
+// refreshGroup makes concurrent callers share one refresh of base URLs.
+type refreshGroup struct {
+	mu   sync.Mutex
+	call *refreshCall
+}
+
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// do calls fn, or waits for the result of a call already in flight.
+func (g *refreshGroup) do(fn func() error) error {
+	g.mu.Lock()
+	if call := g.call; call != nil {
+		g.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	g.call = call
+	g.mu.Unlock()
+
+	call.err = fn()
+
+	g.mu.Lock()
+	g.call = nil
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.err
+}
+
+// BaseURLsExpiry returns the earliest expiry time of base URLs, taken from
+// their 'expire' parameter. It returns false if none of them expire.
+func BaseURLsExpiry(baseURLs map[string]string) (time.Time, bool) {
+	var earliest time.Time
+	for _, baseURL := range baseURLs {
+		raw := urlutil.ExtractParameter(baseURL, "expire")
+		if raw == "" {
+			continue
+		}
+		seconds, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			slog.Debug("bad expire parameter in base URL", "value", raw)
+			continue
+		}
+		expire := time.Unix(seconds, 0)
+		if earliest.IsZero() || expire.Before(earliest) {
+			earliest = expire
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// refreshInBackground refreshes base URLs before they expire until the
+// context is done. It stops if base URLs do not expire or a refresh does not
+// extend their expiry.
+func (pb *Playback) refreshInBackground(ctx context.Context) {
+	for {
+		expire, ok := BaseURLsExpiry(pb.BaseURLs())
+		if !ok {
+			return
+		}
+
+		wait := time.Until(expire.Add(-refreshMargin))
+		slog.Debug("scheduling base URLs refresh", "expire", expire, "in", wait)
+		if !sleepContext(ctx, wait) {
+			return
+		}
+
+		if err := pb.RefreshBaseURLs(); err != nil {
+			slog.Warn("failed to refresh base URLs in background", "err", err)
+			if !sleepContext(ctx, refreshRetryInterval) {
+				return
+			}
+			continue
+		}
+
+		newExpire, ok := BaseURLsExpiry(pb.BaseURLs())
+		if ok && !newExpire.After(expire) {
+			slog.Warn(
+				"refreshed base URLs expire no later, stopping background refresh",
+				"expire", newExpire,
+			)
+			return
+		}
+	}
+}
+
+// sleepContext waits for the duration, returning false if the context is done
+// first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,79 @@
+package segment
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ExtractInit extracts the initialization part (ftyp and moov boxes) from a
+// self-initializing fMP4 segment.
+func ExtractInit(b []byte) ([]byte, error) {
+	var init []byte
+
+	for offset := 0; offset < len(b); {
+		size, boxType, err := readBoxHeader(b[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("reading box at offset %d: %w", offset, err)
+		}
+		if size == 0 {
+			size = len(b) - offset
+		}
+		if offset+size > len(b) {
+			return nil, fmt.Errorf("box '%s' exceeds segment size", boxType)
+		}
+
+		switch boxType {
+		case "ftyp", "moov":
+			init = append(init, b[offset:offset+size]...)
+		case "moof":
+			// Media data starts, no more initialization boxes
+			offset = len(b)
+			continue
+		}
+
+		offset += size
+	}
+
+	if init == nil {
+		return nil, errors.New("no initialization boxes found")
+	}
+
+	return init, nil
+}
+
+// readBoxHeader reads the size and type of an ISO BMFF box. A zero size means
+// that the box extends to the end of data.
+func readBoxHeader(b []byte) (int, string, error) {
+	const headerSize = 8
+
+	if len(b) < headerSize {
+		return 0, "", errors.New("truncated box header")
+	}
+
+	size := uint64(binary.BigEndian.Uint32(b[0:4]))
+	boxType := string(b[4:8])
+
+	switch size {
+	case 0:
+		return 0, boxType, nil
+	case 1:
+		if len(b) < headerSize+8 {
+			return 0, "", errors.New("truncated large box header")
+		}
+		size = binary.BigEndian.Uint64(b[8:16])
+		if size < headerSize+8 {
+			return 0, "", fmt.Errorf("invalid size of box '%s': %d", boxType, size)
+		}
+	default:
+		if size < headerSize {
+			return 0, "", fmt.Errorf("invalid size of box '%s': %d", boxType, size)
+		}
+	}
+
+	if size > uint64(len(b)) {
+		return 0, "", fmt.Errorf("box '%s' exceeds segment size", boxType)
+	}
+
+	return int(size), boxType, nil
+}
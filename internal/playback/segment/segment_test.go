@@ -1,10 +1,12 @@
 package segment_test
 
 import (
+	"encoding/binary"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/xymaxim/ypb/internal/playback/segment"
 )
@@ -33,3 +35,31 @@ Ingestion-Uncertainty-Us: 71`
 	_, err := segment.ParseMetadata([]byte(b))
 	assert.Error(t, err, "should failed for missing 'Ingestion-Walltime-Us'")
 }
+
+func TestExtractInit(t *testing.T) {
+	t.Parallel()
+
+	box := func(boxType string, payload string) []byte {
+		b := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+		return append(append(b, boxType...), payload...)
+	}
+
+	var data []byte
+	data = append(data, box("ftyp", "dash")...)
+	data = append(data, box("emsg", "Sequence-Number: 1")...)
+	data = append(data, box("moov", "tracks")...)
+	data = append(data, box("moof", "fragment")...)
+	data = append(data, box("mdat", "samples")...)
+
+	expected := append(box("ftyp", "dash"), box("moov", "tracks")...)
+	actual, err := segment.ExtractInit(data)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	_, err = segment.ExtractInit(box("mdat", "samples"))
+	assert.Error(t, err, "should fail for missing initialization boxes")
+
+	truncated := data[:len(box("ftyp", "dash"))+len(box("moov", "tracks"))]
+	_, err = segment.ExtractInit(truncated)
+	assert.Error(t, err, "should fail for truncated segment")
+}
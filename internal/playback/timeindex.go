@@ -0,0 +1,101 @@
+package playback
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+// maxIndexEntries bounds the number of segments kept in a time index.
+const maxIndexEntries = 4096
+
+// timeIndex is a sorted index of segments seen so far. It maps sequence
+// numbers to ingestion walltimes and is used to pick a starting point for
+// locating moments. Its size is bounded by evicting the segments that are
+// best predicted by their neighbours. It is safe for concurrent use.
+type timeIndex struct {
+	mu      sync.RWMutex
+	entries []segment.Metadata
+}
+
+// add inserts metadata into the index, keeping entries sorted by sequence
+// number.
+func (idx *timeIndex) add(m segment.Metadata) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	i := sort.Search(len(idx.entries), func(k int) bool {
+		return idx.entries[k].SequenceNumber >= m.SequenceNumber
+	})
+	if i < len(idx.entries) && idx.entries[i].SequenceNumber == m.SequenceNumber {
+		idx.entries[i] = m
+		return
+	}
+	idx.entries = slices.Insert(idx.entries, i, m)
+
+	if len(idx.entries) > maxIndexEntries {
+		idx.evict()
+	}
+}
+
+// evict removes the inner entry whose walltime is the closest to the one
+// interpolated from its neighbours, preferring the densest part of the index
+// on ties. Entries around gaps are poorly predicted and so are kept, as are
+// the first and last entries.
+func (idx *timeIndex) evict() {
+	evicted := -1
+	bestError, bestSpan := time.Duration(math.MaxInt64), math.MaxInt
+	for k := 1; k < len(idx.entries)-1; k++ {
+		before, current, after := idx.entries[k-1], idx.entries[k], idx.entries[k+1]
+		span := after.SequenceNumber - before.SequenceNumber
+		fraction := float64(current.SequenceNumber-before.SequenceNumber) / float64(span)
+		predicted := before.Time().Add(
+			time.Duration(fraction * float64(after.Time().Sub(before.Time()))),
+		)
+		predictionError := current.Time().Sub(predicted).Abs()
+		if predictionError < bestError ||
+			(predictionError == bestError && span < bestSpan) {
+			evicted, bestError, bestSpan = k, predictionError, span
+		}
+	}
+	if evicted >= 0 {
+		idx.entries = slices.Delete(idx.entries, evicted, evicted+1)
+	}
+}
+
+// neighbours returns the known segments closest to t from both sides: the
+// latest one starting not after t and the earliest one starting after t.
+func (idx *timeIndex) neighbours(t time.Time) (before, after *segment.Metadata) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	i := sort.Search(len(idx.entries), func(k int) bool {
+		return idx.entries[k].Time().After(t)
+	})
+	if i > 0 {
+		m := idx.entries[i-1]
+		before = &m
+	}
+	if i < len(idx.entries) {
+		m := idx.entries[i]
+		after = &m
+	}
+	return before, after
+}
+
+// interpolateSeqNum estimates the sequence number of the segment containing t
+// from two segments around it. Unlike dividing by a nominal segment duration,
+// this accounts for gaps between them.
+func interpolateSeqNum(t time.Time, before, after segment.Metadata) SequenceNumber {
+	span := after.Time().Sub(before.Time())
+	if span <= 0 {
+		return before.SequenceNumber
+	}
+	fraction := float64(t.Sub(before.Time())) / float64(span)
+	offset := int(math.Floor(fraction * float64(after.SequenceNumber-before.SequenceNumber)))
+	return min(max(before.SequenceNumber+offset, before.SequenceNumber), after.SequenceNumber)
+}
@@ -0,0 +1,49 @@
+package playback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xymaxim/ypb/internal/playback/segment"
+)
+
+func TestTimeIndex_Bounded(t *testing.T) {
+	t.Parallel()
+
+	const (
+		count    = 2 * maxIndexEntries
+		gapAfter = maxIndexEntries / 2
+		gap      = time.Hour
+	)
+	start := time.Date(2026, 1, 2, 10, 20, 30, 0, time.UTC)
+	walltime := func(sq int) time.Time {
+		at := start.Add(time.Duration(sq) * 2 * time.Second)
+		if sq > gapAfter {
+			at = at.Add(gap)
+		}
+		return at
+	}
+
+	var idx timeIndex
+	for sq := range count {
+		idx.add(segment.Metadata{
+			SequenceNumber:    sq,
+			IngestionWalltime: walltime(sq),
+			Duration:          2 * time.Second,
+		})
+	}
+
+	require.Len(t, idx.entries, maxIndexEntries)
+	assert.Equal(t, 0, idx.entries[0].SequenceNumber)
+	assert.Equal(t, count-1, idx.entries[len(idx.entries)-1].SequenceNumber)
+
+	// Both sides of the gap are kept
+	before, after := idx.neighbours(walltime(gapAfter).Add(gap / 2))
+	require.NotNil(t, before)
+	require.NotNil(t, after)
+	assert.Equal(t, gapAfter, before.SequenceNumber)
+	assert.Equal(t, gapAfter+1, after.SequenceNumber)
+}
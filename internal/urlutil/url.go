@@ -3,9 +3,19 @@ package urlutil
 import (
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 )
 
+// videoIDPattern matches YouTube video IDs: 11 characters of the URL-safe
+// base64 alphabet.
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// IsVideoID reports whether s is a well-formed YouTube video ID.
+func IsVideoID(s string) bool {
+	return videoIDPattern.MatchString(s)
+}
+
 func BuildVideoURL(id string) string {
 	return "https://www.youtube.com/watch?v=" + id
 }
@@ -16,6 +16,8 @@ type RewindMoment = internalplayback.RewindMoment
 
 type RewindInterval = internalplayback.RewindInterval
 
+type Gap = internalplayback.Gap
+
 type SegmentMetadata = segment.Metadata
 
 type VideoInformation = info.VideoInformation
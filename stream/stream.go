@@ -51,9 +51,23 @@ func NewStream(ctx context.Context, videoID string, port int, cfg *StreamConfig)
 			ServerAddr:    app.Server.Addr,
 		}).ServeHTTP),
 	)
+	mux.HandleFunc(apppkg.GapsPath, apppkg.WithError(
+		(&apppkg.GapsHandler{Playback: app.Playback}).ServeHTTP),
+	)
+	hlsHandler := &apppkg.HLSHandler{
+		Playback:   app.Playback,
+		Gaps:       apppkg.NewGapScans(),
+		ServerAddr: app.Server.Addr,
+	}
+	mux.HandleFunc(apppkg.HLSPath, apppkg.WithError(hlsHandler.ServeMaster))
+	mux.HandleFunc(apppkg.HLSMediaPath, apppkg.WithError(hlsHandler.ServeMedia))
 	mux.HandleFunc(apppkg.SegmentPath, apppkg.WithError(
 		(&apppkg.SegmentHandler{Playback: app.Playback}).ServeHTTP),
 	)
+	mux.HandleFunc(apppkg.InitPath, apppkg.WithError(
+		(&apppkg.InitHandler{Playback: app.Playback}).ServeHTTP),
+	)
+	mux.HandleFunc(apppkg.TimePath, apppkg.WithError((&apppkg.TimeHandler{}).ServeHTTP))
 	app.Server.Handler = mux
 
 	stream := &Stream{